@@ -0,0 +1,62 @@
+package psjungle
+
+import "fmt"
+
+// subtreeTotal is the summed load of a process and all of its descendants
+type subtreeTotal struct {
+	CPU float64 `json:"cpu"`
+	RSS uint64  `json:"rss"`
+	PSS uint64  `json:"pss,omitempty"`
+
+	// Partial is set with --aggregate-mem pss when the PSS of some process in the
+	// subtree could not be read, so the PSS total is too low
+	Partial bool `json:"partial,omitempty"`
+
+	// byPSS shows the PSS total instead of RSS, see --aggregate-mem
+	byPSS bool
+}
+
+// String formats the totals for a tree line, e.g. "[Σ 340.0% 12.4GB]"
+// or "[Σ 340.0% 3.10GB pss]" with --aggregate-mem pss, where a partial
+// total is marked with a tilde: "[Σ 340.0% ~3.10GB pss]"
+func (t subtreeTotal) String() string {
+	if t.byPSS {
+		partial := ""
+		if t.Partial {
+			partial = "~"
+		}
+		return fmt.Sprintf("[Σ %.1f%% %s%s pss]", t.CPU, partial, formatMemory(t.PSS/1024))
+	}
+	return fmt.Sprintf("[Σ %.1f%% %s]", t.CPU, formatMemory(t.RSS/1024))
+}
+
+// attachTotals fills in the subtree totals of each target and its descendants for --aggregate.
+// Ancestors are skipped: only the branch leading to the target is shown for them, so their
+// totals would leave out the rest of their children. Leaves are skipped as well, since
+// their total is their own load.
+func attachTotals(trees []*targetTree, byPSS bool) {
+	for _, t := range trees {
+		if target := findTargetNode(t.root, t.pid); target != nil {
+			sumSubtree(target, byPSS)
+		}
+	}
+}
+
+// sumSubtree returns the totals of node and its descendants, storing them on every node with children
+func sumSubtree(node *ProcessNode, byPSS bool) subtreeTotal {
+	info := getProcessInfo(node)
+	total := subtreeTotal{CPU: info.CPU, RSS: info.RSS, PSS: info.PSS, Partial: byPSS && info.pssUnread, byPSS: byPSS}
+	if len(node.Children) == 0 {
+		return total
+	}
+
+	for _, child := range node.Children {
+		childTotal := sumSubtree(child, byPSS)
+		total.CPU += childTotal.CPU
+		total.RSS += childTotal.RSS
+		total.PSS += childTotal.PSS
+		total.Partial = total.Partial || childTotal.Partial
+	}
+	node.info.Total = &total
+	return total
+}
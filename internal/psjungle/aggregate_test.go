@@ -0,0 +1,35 @@
+package psjungle
+
+import "testing"
+
+func TestSumSubtreePartialPSS(t *testing.T) {
+	// 1
+	// ├── 2 (PSS unreadable)
+	// └── 3
+	//     └── 4
+	root := testNode(processInfo{Pid: 1, RSS: 4 << 20, PSS: 2 << 20},
+		testNode(processInfo{Pid: 2, RSS: 4 << 20, pssUnread: true}),
+		testNode(processInfo{Pid: 3, RSS: 4 << 20, PSS: 1 << 20},
+			testNode(processInfo{Pid: 4, RSS: 4 << 20, PSS: 1 << 20}),
+		),
+	)
+
+	total := sumSubtree(root, true)
+	if total.PSS != 4<<20 || total.RSS != 16<<20 {
+		t.Errorf("expected 4MB PSS and 16MB RSS in total, got %d and %d", total.PSS, total.RSS)
+	}
+	if !total.Partial {
+		t.Errorf("expected the total of PID 1 to be partial")
+	}
+	if got := total.String(); got != "[Σ 0.0% ~4.10MB pss]" {
+		t.Errorf("expected the partial total to be marked, got %q", got)
+	}
+	if branch := root.Children[1].info.Total; branch == nil || branch.Partial {
+		t.Errorf("expected a complete total for PID 3, got %+v", branch)
+	}
+
+	// RSS totals don't depend on PSS
+	if total := sumSubtree(root, false); total.Partial {
+		t.Errorf("expected RSS totals never to be partial")
+	}
+}
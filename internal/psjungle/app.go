@@ -1,7 +1,9 @@
 package psjungle
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"runtime"
@@ -21,6 +23,13 @@ type ProcessNode struct {
 	Depth    int
 	IsTarget bool
 	Parent   *ProcessNode
+
+	// info caches the displayable fields, see getProcessInfo
+	info *processInfo
+	// Set in watch mode by comparing with the previous refresh, see changeTracker
+	appeared bool
+	exited   bool
+	swung    bool
 }
 
 // getAllProcesses returns a map of all processes indexed by PID
@@ -408,7 +417,7 @@ func defineFlags() []cli.Flag {
 			Name:    "watch",
 			Aliases: []string{"w"},
 			Value:   "",
-			Usage:   "Watch mode with refresh interval. Use formats like -w=2, -w2, or -w 2 for 2 seconds refresh. Provide a PID/port/name to watch (only watches the first target when multiple targets are specified).",
+			Usage:   "Watch mode with refresh interval. Use formats like -w=2, -w2, or -w 2 for 2 seconds refresh. Provide one or more PIDs/ports/names to watch; all of them are re-resolved on every refresh and processes that appeared (+), exited (-, greyed) or had large CPU/RSS swings (bold) since the previous refresh are highlighted.",
 		},
 		&cli.BoolFlag{
 			Name:    "flat",
@@ -426,7 +435,63 @@ func defineFlags() []cli.Flag {
 			Name:    "host",
 			Aliases: []string{"H"},
 			Value:   "",
-			Usage:   "Filter port connections by specific host (e.g., 127.0.0.1 or 0.0.0.0). Only applies to :port syntax.",
+			Usage:   "Filter port connections by host: an IPv4/IPv6 address (127.0.0.1, ::1), a CIDR range (10.0.0.0/8), an interface name (eth0) or localhost. 0.0.0.0, :: and * only match sockets bound to all interfaces. Only applies to :port syntax.",
+		},
+		&cli.StringFlag{
+			Name:  "proto",
+			Value: "",
+			Usage: "Only match port connections of this protocol: tcp, udp, tcp6 or udp6. Only applies to :port syntax.",
+		},
+		&cli.StringFlag{
+			Name:  "state",
+			Value: "",
+			Usage: "Only match port connections in this state, e.g. listen, established or time_wait. Only applies to :port syntax.",
+		},
+		&cli.BoolFlag{
+			Name:  "remote",
+			Value: false,
+			Usage: "Match processes connected TO the port (its clients) instead of the ones serving it. --host then filters the remote address. Only applies to :port syntax.",
+		},
+		&cli.StringFlag{
+			Name:    "user",
+			Aliases: []string{"u"},
+			Value:   "",
+			Usage:   "Only show matches owned by these users (comma-separated names). Without other inputs, shows all of their processes",
+		},
+		&cli.StringFlag{
+			Name:  "uid",
+			Value: "",
+			Usage: "Only show matches owned by these UIDs (comma-separated). Without other inputs, shows all of their processes",
+		},
+		&cli.StringFlag{
+			Name:  "group",
+			Value: "",
+			Usage: "Only show matches whose group is one of these names or GIDs (comma-separated). Without other inputs, shows all of their processes",
+		},
+		&cli.StringFlag{
+			Name:  "container",
+			Value: "",
+			Usage: "Only show matches running in these containers (comma-separated names or ID prefixes; Docker, containerd, CRI-O or podman, Linux only). Without other inputs, shows all of their processes",
+		},
+		&cli.BoolFlag{
+			Name:  "group-by-cgroup",
+			Value: false,
+			Usage: "Group the trees by the cgroup (and container) of their target process (Linux only)",
+		},
+		&cli.StringFlag{
+			Name:  "where",
+			Value: "",
+			Usage: "Only show processes matching an expression, e.g. 'cpu > 50 && rss > 500MB && name =~ \"java\"'. Fields: pid, ppid, uid, user, name, cmd, exe, cpu, rss (mem), vsz, threads, age. Without other inputs, all processes are checked",
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Value: 0,
+			Usage: "Only show the N heaviest processes (see --by), each in context with its ancestors and children. Without other inputs, all processes are ranked",
+		},
+		&cli.StringFlag{
+			Name:  "by",
+			Value: "cpu",
+			Usage: "Resource used to rank processes for --top: cpu or mem",
 		},
 		&cli.StringFlag{
 			Name:    "kill",
@@ -434,6 +499,79 @@ func defineFlags() []cli.Flag {
 			Value:   "",
 			Usage:   "Send signal to matching processes. Use formats like -k, -k=9, -k term. Only sends signal after displaying tree.",
 		},
+		&cli.BoolFlag{
+			Name:  "kill-tree",
+			Value: false,
+			Usage: "Send the -k (or --kill-grace) signal to every process in the subtree of each match, not just the match. PIDs reused since the tree was read are skipped",
+		},
+		&cli.StringFlag{
+			Name:  "kill-order",
+			Value: killOrderRoot,
+			Usage: "Order in which --kill-tree signals a subtree: root (parents before their children, so supervisors can't respawn them) or leaves",
+		},
+		&cli.DurationFlag{
+			Name:  "kill-grace",
+			Usage: "Send the -k signal (SIGTERM without -k), wait up to this long for the processes to exit while showing the ones still running, then send SIGKILL to them and report what happened to each PID, e.g. --kill-grace 10s",
+		},
+		&cli.StringFlag{
+			Name:    "columns",
+			Aliases: []string{"c"},
+			Value:   "",
+			Usage:   "Comma-separated fields to print per process: pid, ppid, user, cpu, rss (mem), vsz, threads, start, name, cmd. Default: pid,user,cpu,rss,cmd",
+		},
+		&cli.StringFlag{
+			Name:    "format",
+			Aliases: []string{"F"},
+			Value:   "",
+			Usage:   "Go template for each process line, e.g. '{{.Pid}} {{.User}} {{.Cmdline}}'. Fields: Pid, Ppid, User, Name, Cmdline, CPU, RSS, VSZ, Threads, Start, IsTarget. Functions: mem, start",
+		},
+		&cli.DurationFlag{
+			Name:  "sample",
+			Value: 500 * time.Millisecond,
+			Usage: "Measure CPU% over this interval like top (watch mode uses the time between refreshes). 0 shows the lifetime average instead",
+		},
+		&cli.StringFlag{
+			Name:  "sort",
+			Value: "pid",
+			Usage: "Order the children of each process by cpu, mem, pid, start or name. cpu and mem put the heaviest first",
+		},
+		&cli.BoolFlag{
+			Name:    "reverse",
+			Aliases: []string{"r"},
+			Value:   false,
+			Usage:   "Reverse the --sort order",
+		},
+		&cli.BoolFlag{
+			Name:  "sockets",
+			Value: false,
+			Usage: "List the sockets of each process below it: protocol, local and remote address, and state",
+		},
+		&cli.BoolFlag{
+			Name:  "files",
+			Value: false,
+			Usage: "List the open file descriptors of each process below it: fd number, access mode, type and path",
+		},
+		&cli.BoolFlag{
+			Name:  "threads",
+			Value: false,
+			Usage: "Show the threads of each multi-threaded process as leaves below it: TID, CPU%, state and name (Linux only)",
+		},
+		&cli.BoolFlag{
+			Name:  "aggregate",
+			Value: false,
+			Usage: "Show the summed CPU% and memory of the whole subtree before the target and each of its descendants with children, e.g. [Σ 340.0% 12.4GB]",
+		},
+		&cli.StringFlag{
+			Name:  "aggregate-mem",
+			Value: aggregateRSS,
+			Usage: "Memory summed by --aggregate: rss, or pss (Linux only) to divide shared pages among the processes sharing them instead of counting them once per process",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Value:   "text",
+			Usage:   "Output format: text or json. JSON serializes each displayed tree with its target PID (one document per refresh in watch mode).",
+		},
 	}
 }
 
@@ -489,37 +627,92 @@ func BuildTreePrefix(node *ProcessNode, nextSiblings []*ProcessNode, flatMode bo
 	return prefix.String()
 }
 
-// printNodeWithTree prints the process tree nodes with proper indentation
-func printNodeWithTree(node *ProcessNode, targetPid int, nextSiblings []*ProcessNode, flatMode bool) {
+// childIndent returns the tree prefix of a node with its connector replaced by the
+// vertical line of its later siblings, where the connectors of its children start
+func childIndent(node *ProcessNode, nextSiblings []*ProcessNode, flatMode bool) string {
 	prefix := BuildTreePrefix(node, nextSiblings, flatMode)
+	if strings.HasSuffix(prefix, "├── ") {
+		return strings.TrimSuffix(prefix, "├── ") + "│   "
+	} else if strings.HasSuffix(prefix, "└── ") {
+		return strings.TrimSuffix(prefix, "└── ") + "    "
+	}
+	return prefix
+}
 
-	// Get process info
-	pid := node.Process.Pid
-	name, _ := node.Process.Name()
-	cmdline, _ := node.Process.Cmdline()
-	cpuPercent, _ := node.Process.CPUPercent()
-	memInfo, _ := node.Process.MemoryInfo()
-	var rss uint64
-	if memInfo != nil {
-		rss = memInfo.RSS / 1024 // Convert to KB
+// detailPrefix returns the indentation for lines printed below a node, such as its
+// sockets, continuing the tree lines of the node and its children
+func detailPrefix(node *ProcessNode, nextSiblings []*ProcessNode, flatMode bool) string {
+	if flatMode {
+		return "    "
 	}
 
-	if cmdline == "" {
-		cmdline = name
+	prefix := childIndent(node, nextSiblings, flatMode)
+	if len(node.Children) > 0 || len(getProcessInfo(node).Tasks) > 0 {
+		return prefix + "│   "
 	}
+	return prefix + "    "
+}
 
-	// Filter out extra macOS kernel details from command line
-	cmdline = filterMacOSKernelDetails(cmdline)
+// nodeDetails returns the extra lines shown below a node: its sockets with --sockets
+// and its open files with --files
+func nodeDetails(node *ProcessNode, opts *options) []string {
+	// Exited processes are only shown for one refresh and hold nothing anymore
+	if node.exited {
+		return nil
+	}
+
+	var details []string
+	if opts.sockets {
+		for _, s := range getProcessInfo(node).Sockets {
+			details = append(details, s.String())
+		}
+	}
+	if opts.files {
+		for _, f := range getProcessInfo(node).Files {
+			details = append(details, f.String())
+		}
+	}
+	return details
+}
 
-	// Format memory usage in human-readable way
-	memStr := formatMemory(rss)
+// printNodeWithTree prints the process tree nodes with proper indentation
+func printNodeWithTree(node *ProcessNode, targetPid int, nextSiblings []*ProcessNode, opts *options) {
+	prefix := BuildTreePrefix(node, nextSiblings, opts.flatMode)
 
-	// Print the process with highlighting if it's the target PID
-	// Format similar to ps aux: PID, CPU%, MEM%, COMMAND
-	if node.IsTarget {
-		fmt.Printf("%s\033[32m%d %.1f %s %s\033[0m\n", prefix, pid, cpuPercent, memStr, cmdline)
+	// Get process info
+	line := formatNodeLine(getProcessInfo(node), opts)
+
+	// In watch mode, a gutter marks processes that appeared or exited since the previous refresh
+	if opts.changes.enabled() {
+		prefix = changeMarker(node) + prefix
+	}
+
+	// Print the process with highlighting if it's the target PID or changed since the previous refresh
+	if style := nodeStyle(node); style != "" {
+		fmt.Printf("%s%s%s\033[0m\n", prefix, style, line)
 	} else {
-		fmt.Printf("%s%d %.1f %s %s\n", prefix, pid, cpuPercent, memStr, cmdline)
+		fmt.Printf("%s%s\n", prefix, line)
+	}
+
+	// Print sockets and other details below the process, lined up with its children
+	if details := nodeDetails(node, opts); len(details) > 0 {
+		detailIndent := detailPrefix(node, nextSiblings, opts.flatMode)
+		if opts.changes.enabled() {
+			detailIndent = "  " + detailIndent
+		}
+		for _, detail := range details {
+			fmt.Printf("%s\033[2m%s\033[0m\n", detailIndent, detail)
+		}
+	}
+
+	// Print threads as leaves before the child processes
+	threads := nodeThreads(node, opts)
+	for i, thread := range threads {
+		threadIndent := threadPrefix(node, nextSiblings, i == len(threads)-1 && len(node.Children) == 0, opts.flatMode)
+		if opts.changes.enabled() {
+			threadIndent = "  " + threadIndent
+		}
+		fmt.Printf("%s%s\n", threadIndent, thread)
 	}
 
 	// Print children with proper tree characters
@@ -530,7 +723,7 @@ func printNodeWithTree(node *ProcessNode, targetPid int, nextSiblings []*Process
 		for j := i + 1; j < len(node.Children); j++ {
 			siblings = append(siblings, node.Children[j])
 		}
-		printNodeWithTree(child, targetPid, siblings, flatMode)
+		printNodeWithTree(child, targetPid, siblings, opts)
 	}
 }
 
@@ -549,28 +742,74 @@ func findTargetNode(node *ProcessNode, targetPid int) *ProcessNode {
 	return nil
 }
 
-// pstreeBoth displays the process tree for a given PID using gopsutil
-func pstreeBoth(targetPid int, flatMode bool) error {
+// targetTree is a focused process tree built for one target PID
+type targetTree struct {
+	pid  int
+	root *ProcessNode
+}
+
+// pstreeBoth builds the focused process tree for a given PID using gopsutil
+func pstreeBoth(targetPid int) (*ProcessNode, error) {
 	// Get all processes
 	procMap, err := getAllProcesses()
 	if err != nil {
-		return fmt.Errorf("failed to get all processes: %v", err)
+		return nil, fmt.Errorf("failed to get all processes: %v", err)
 	}
 
 	// Check if target process exists
 	_, err = process.NewProcess(int32(targetPid))
 	if err != nil {
-		return fmt.Errorf("target process %d not found", targetPid)
+		return nil, fmt.Errorf("target process %d not found", targetPid)
 	}
 
 	// Build the focused tree containing the target process, its ancestors, and descendants
 	tree := buildFocusedTree(targetPid, procMap)
 	if tree == nil {
-		return fmt.Errorf("failed to build process tree for PID %d", targetPid)
+		return nil, fmt.Errorf("failed to build process tree for PID %d", targetPid)
+	}
+
+	return tree, nil
+}
+
+// renderTrees prints the trees in the selected output format
+func renderTrees(trees []*targetTree, multiple bool, opts *options) error {
+	if opts.jsonOutput() {
+		return writeJSONTrees(os.Stdout, trees, !opts.watch)
+	}
+
+	groups := []*treeGroup{{trees: trees}}
+	if opts.byCgroup {
+		groups = groupTrees(trees)
+	}
+
+	first := true
+	for _, group := range groups {
+		if group.label != "" {
+			if !first {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", group.label)
+		}
+		for i, t := range group.trees {
+			if !first && (i > 0 || group.label == "") {
+				fmt.Println()
+			}
+			first = false
+			if multiple {
+				fmt.Printf("Process tree for PID %d:\n", t.pid)
+			}
+			// Print the entire tree (it's already focused)
+			printNodeWithTree(t.root, t.pid, []*ProcessNode{}, opts)
+		}
 	}
 
-	// Print the entire tree (it's already focused)
-	printNodeWithTree(tree, targetPid, []*ProcessNode{}, flatMode)
+	if opts.changes.enabled() && len(opts.changes.exited) > 0 {
+		fmt.Println()
+		fmt.Println("Exited since last refresh:")
+		for _, info := range opts.changes.exited {
+			fmt.Printf("\033[90m%s\033[0m\n", formatExitedLine(info, opts))
+		}
+	}
 	return nil
 }
 
@@ -642,78 +881,159 @@ func getProcessTreePids(targetPid int) []int {
 }
 
 // parseInputs determines which processes to display trees for based on input arguments.
+// Each input is resolved on its own as a PID, a :port or a pattern, and the results are
+// combined in input order without duplicates.
+// With --top, only the heaviest of the matches are kept, heaviest first.
 // Returns a list of PIDs to process.
-func parseInputs(inputs []string, strictMode bool, host string) ([]int, error) {
+func parseInputs(inputs []string, opts *options) ([]int, error) {
 	var allPids []int
-	var err error
-
-	// If we have multiple inputs, treat them all as PIDs
-	if len(inputs) > 1 {
-		for _, input := range inputs {
-			// Check if input is a PID (only numbers)
-			if regexp.MustCompile(`^\d+$`).MatchString(input) {
-				pid, convErr := strconv.Atoi(input)
-				if convErr != nil {
-					return nil, fmt.Errorf("invalid PID '%s'", input)
-				}
+	if len(inputs) == 0 {
+		// Filters alone, such as --user, --where or --top, are applied to every process
+		if !opts.selectsWithoutInputs() {
+			return nil, fmt.Errorf("no input provided")
+		}
+		pids, err := AllPids()
+		if err != nil {
+			return nil, err
+		}
+		allPids = pids
+	}
+
+	seen := make(map[int]bool)
+	for _, input := range inputs {
+		pids, err := resolveInput(input, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, pid := range pids {
+			if !seen[pid] {
+				seen[pid] = true
 				allPids = append(allPids, pid)
-			} else {
-				// For non-numeric inputs when we have multiple arguments,
-				// we could extend this but for now we'll treat them as invalid
-				return nil, fmt.Errorf("invalid PID '%s'", input)
 			}
 		}
-	} else if len(inputs) == 1 {
-		// Single input - use existing logic
-		input := inputs[0]
-		var pids []int
+	}
 
-		// Check if input is a PID (only numbers)
-		if regexp.MustCompile(`^\d+$`).MatchString(input) {
-			pid, convErr := strconv.Atoi(input)
-			if convErr != nil {
-				return nil, fmt.Errorf("invalid PID '%s'", input)
-			}
-			pids = []int{pid}
-		} else if strings.HasPrefix(input, ":") {
-			// Port matching
-			port := strings.TrimPrefix(input, ":")
-			portNum, convErr := strconv.Atoi(port)
-			if convErr != nil || portNum < 0 || portNum > 65535 {
-				return nil, fmt.Errorf("invalid port '%s'", port)
+	allPids = FilterPids(allPids, opts.predicates()...)
+	if opts.top != nil {
+		allPids = opts.top.pick(allPids)
+	}
+	return allPids, nil
+}
+
+// resolveInput returns the PIDs matching a single input argument
+func resolveInput(input string, opts *options) ([]int, error) {
+	// Check if input is a PID (only numbers)
+	if regexp.MustCompile(`^\d+$`).MatchString(input) {
+		pid, err := strconv.Atoi(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PID '%s'", input)
+		}
+		return []int{pid}, nil
+	}
+
+	if strings.HasPrefix(input, ":") {
+		// Port matching: a single port, a range or a comma-separated list of both
+		ports, err := parsePorts(strings.TrimPrefix(input, ":"))
+		if err != nil {
+			return nil, err
+		}
+		query := opts.portQuery
+		query.Ports = ports
+		return ByPortQuery(query)
+	}
+
+	// Processes of a systemd unit, e.g. unit:nginx.service
+	if name, ok := strings.CutPrefix(input, "unit:"); ok {
+		return ByUnit(name)
+	}
+
+	// Processes of a PID namespace, e.g. ns:4026532345, or one of them by its PID inside it
+	if spec, ok := strings.CutPrefix(input, "ns:"); ok {
+		inode, nspid, err := parseNamespaceTarget(spec)
+		if err != nil {
+			return nil, err
+		}
+		return ByNamespace(inode, nspid)
+	}
+
+	// Field selectors such as name=nginx or env=RAILS_ENV=production
+	if field, value, ok := strings.Cut(input, "="); ok && isSelectorField(field) {
+		return ByField(field, value, opts.strictMode)
+	}
+
+	if strings.HasPrefix(input, "@") {
+		// Processes holding a file open
+		return ByFile(strings.TrimPrefix(input, "@"))
+	}
+
+	// An existing absolute path is looked up as a file first, and as a pattern
+	// when no process has it open
+	if strings.HasPrefix(input, "/") {
+		if _, err := os.Stat(input); err == nil {
+			pids, err := ByFile(input)
+			if err != nil || len(pids) > 0 {
+				return pids, err
 			}
-			pids, err = ByPort(uint32(portNum), host)
-			if err != nil {
+		}
+	}
+
+	// Regex or strict string matching
+	return ByRegex(input, opts.strictMode)
+}
+
+// isSelectorField reports whether name is one of the fields accepted by ByField
+func isSelectorField(name string) bool {
+	for _, field := range SelectorFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePorts parses the port part of a :port input, such as 8080, 8000-8100 or 80,443,8443
+func parsePorts(spec string) ([]uint32, error) {
+	var ports []uint32
+	for _, part := range strings.Split(spec, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := parsePort(first)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			if end, err = parsePort(last); err != nil {
 				return nil, err
 			}
-		} else {
-			// Regex or strict string matching
-			pids, err = ByRegex(input, strictMode)
-			if err != nil {
-				return nil, err
+			if end < start {
+				return nil, fmt.Errorf("invalid port range '%s'", part)
 			}
 		}
-
-		allPids = pids
-	} else {
-		return nil, fmt.Errorf("no input provided")
+		for port := start; port <= end; port++ {
+			ports = append(ports, port)
+		}
 	}
+	return ports, nil
+}
 
-	if len(allPids) == 0 {
-		fmt.Println("No processes found")
-		os.Exit(1)
+// parsePort parses a single port number
+func parsePort(port string) (uint32, error) {
+	portNum, err := strconv.Atoi(port)
+	if err != nil || portNum < 0 || portNum > 65535 {
+		return 0, fmt.Errorf("invalid port '%s'", port)
 	}
-
-	return allPids, nil
+	return uint32(portNum), nil
 }
 
+// errNoProcesses is returned by runPstree when none of the inputs matched a process
+var errNoProcesses = errors.New("no processes found")
+
 // runPstree dispatches based on user input and prints matching trees.
-// When multiple inputs are provided, they are all treated as PIDs.
-// Returns the list of PIDs that were processed.
-func runPstree(inputs []string, flatMode bool, strictMode bool, host string) ([]int, error) {
-	allPids, err := parseInputs(inputs, strictMode, host)
+// Returns the trees and the list of PIDs that were processed.
+func runPstree(inputs []string, opts *options) ([]*targetTree, []int, error) {
+	allPids, err := parseInputs(inputs, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// For multiple PIDs, we want to avoid showing duplicate trees
@@ -721,51 +1041,120 @@ func runPstree(inputs []string, flatMode bool, strictMode bool, host string) ([]
 	shownPids := make(map[int]bool)
 
 	// Display process trees for all PIDs, but avoid duplicates
-	// Keep track of which PIDs we actually displayed trees for
-	return displayProcessTrees(allPids, flatMode, shownPids)
+	// Keep track of which PIDs we actually displayed trees for.
+	// This also runs without any PID: JSON consumers still get an empty array, and
+	// in watch mode the processes that just exited are shown one last time.
+	trees, processedPids, err := displayProcessTrees(allPids, opts, shownPids)
+	if err == nil && len(allPids) == 0 {
+		fmt.Fprintln(opts.messages(), "No processes found")
+		err = errNoProcesses
+	}
+	return trees, processedPids, err
 }
 
 // appUsageText contains the extensive usage documentation for psjungle
-const appUsageText = `psjungle [options] [PID|:port|pattern]...
+const appUsageText = `psjungle [options] [PID|:port|@file|unit:name|ns:inode|field=value|pattern]...
 
 EXAMPLES:
    psjungle 1234               Display process tree for PID 1234
    psjungle :8080              Display process trees for processes listening on port 8080
-   psjungle :8080 --host 127.0.0.1  Display process trees for processes listening on port 8080 on localhost only
-   psjungle :8080 --host 0.0.0.0    Display process trees for processes listening on port 8080 on all interfaces
+   psjungle :8000-8100         Display process trees for processes on any port from 8000 to 8100
+   psjungle :80,443,8443       Display process trees for processes on any of the listed ports
+   psjungle --host 127.0.0.1 :8080  Display process trees for processes listening on port 8080 on localhost only
+   psjungle --host 0.0.0.0 :8080    Display process trees for processes listening on port 8080 on all interfaces
+   psjungle --host 10.0.0.0/8 :8080 Display process trees for processes listening on port 8080 on a 10.x address
+   psjungle --host eth0 :8080       Display process trees for processes listening on port 8080 on eth0's addresses
+   psjungle --state listen :5432    Display process trees for the processes serving port 5432
+   psjungle --remote :5432          Display process trees for the clients connected to port 5432
+   psjungle --proto udp :53         Display process trees for processes with UDP sockets on port 53
    psjungle node               Display process trees for processes matching "node" (regex pattern)
    psjungle "node.*8080"        Display process trees for processes matching regex pattern
    psjungle -s "node.*8080"    Display process trees for processes with exact string "node.*8080" in name or command line
    psjungle 1234 5678          Display process trees for multiple PIDs (intelligently shows separate trees only when needed)
    psjungle 1234 5678 9012     Display process trees for three PIDs
    psjungle 1 1234 4321        Display process trees for root process and two other PIDs
+   psjungle :8080 nginx 4242 :5432   Mix PIDs, ports and patterns in a single invocation
    psjungle -w 1234            Watch process tree for PID 1234 (refresh every 2 seconds)
    psjungle -w=5 :3000          Watch processes listening on port 3000 (refresh every 5 seconds)
    psjungle -w2 1234           Watch process tree for PID 1234 (refresh every 2 seconds)
    psjungle -s -w2 starman     Watch process trees for processes with "starman" in name or command line
+   psjungle -w2 nginx :5432    Watch nginx and the processes on port 5432, marking new (+) and exited (-) processes
    psjungle -k 1234            Display process tree for PID 1234 and send SIGTERM to it
    psjungle -k=9 :8080         Display process trees for processes on port 8080 and send SIGKILL to them
    psjungle -k hup node        Display process trees for processes matching "node" and send SIGHUP to them
+   psjungle -k --kill-tree supervisord   Send SIGTERM to supervisord and every process below it, parents first
+   psjungle -k=9 --kill-tree --kill-order leaves 1234   Send SIGKILL to the tree of PID 1234, children first
+   psjungle --kill-grace 10s :8080   Send SIGTERM to the processes on port 8080, then SIGKILL to those still running after 10s
+   psjungle -o json nginx      Print process trees for processes matching "nginx" as JSON
+   psjungle -c pid,user,cpu,rss,threads,start,cmd gunicorn   Choose which fields are printed per process
+   psjungle --format '{{.Pid}} {{.User}} {{.Cmdline}}' node  Print each process using a Go template
+   psjungle --sort cpu gunicorn   Show the busiest gunicorn workers first
+   psjungle --sample 2s java      Measure CPU% over 2 seconds
+   psjungle --sockets :8080    Display the trees for port 8080 with the sockets of every process listed below it
+   psjungle name=nginx         Display process trees for processes named exactly "nginx"
+   psjungle env=RAILS_ENV=production   Display process trees for processes with RAILS_ENV=production
+   psjungle unit:nginx.service   Display process trees for the processes of the nginx systemd unit
+   psjungle -c pid,unit,slice,cmd node   Show the systemd unit and slice owning each process
+   psjungle ns:4026532345      Display process trees for every process in PID namespace 4026532345
+   psjungle ns:4026532345:42   Display the process tree for the process with PID 42 inside that namespace
+   psjungle @/var/log/app.log  Display process trees for processes holding the file open
+   psjungle --files 1234       Display the tree for PID 1234 with the open files of every process
+   psjungle --threads --sort cpu java   Display java trees with the threads of each process, busiest first
+   psjungle --aggregate gunicorn   Display gunicorn trees with the total CPU% and memory of each subtree
+   psjungle -c pid,rss,pss,uss,cmd starman   Display the proportional and unique memory of each process (Linux)
+   psjungle --where 'rss > 1GB'   Display process trees for every process using more than 1GB of memory
+   psjungle --where 'cpu > 50 && name =~ "java"'   Display process trees for busy Java processes
+   psjungle --top 10 --by mem   Display the 10 processes using the most memory, each in its tree
+   psjungle --container web node   Display process trees for node processes running in the "web" container
+   psjungle --group-by-cgroup node   Display process trees for node processes grouped by cgroup and container
+   psjungle --user ci python   Display process trees for python processes owned by user "ci"
+   psjungle --uid 1000 --group docker   Display process trees for all processes of UID 1000 running as group docker
 
 By default, patterns are treated as regex. Use the -s/--strict flag to match exact strings.
-When multiple arguments are provided, they are all treated as PIDs and psjungle intelligently
-shows separate process trees only when needed (when PIDs are not in the same process tree).
+Use field=value to match a single field instead of the command line: name, exe, cwd, env
+(KEY=VALUE), user or arg0. The value has to match the whole field.
+Prefix a path with @ (or give an existing absolute path) to find the processes holding it open.
+Use unit:name to find the processes of a systemd unit (Linux), e.g. unit:nginx.service or unit:nginx.
+Use ns:inode to find the processes of a PID namespace (Linux), and ns:inode:PID for the process with
+that PID inside it. Processes in another PID namespace show their PID inside it after the host PID, e.g. 2360[1].
+Multiple arguments can mix PIDs, ports, files and patterns; the matches are combined and psjungle
+intelligently shows separate process trees only when needed (when PIDs are not in the same process tree).
 Use the --host flag to filter port connections by specific host. Only applies to :port syntax.
-Use the --kill/-k flag to send signals to matching processes after displaying trees.
-
-Output format: PID CPU% Memory CommandLine
+Use --proto and --state to narrow port matches by protocol and connection state, and --remote
+to find the clients of a port instead of the processes serving it.
+Use --where to keep processes matching an expression over pid, ppid, uid, user, name, cmd, exe,
+cpu, rss, vsz, threads and age; on its own it checks every process.
+Use --top N to only show the N heaviest matches by --by cpu (default) or mem, heaviest first;
+on its own it ranks every process.
+Use --container to only keep matches running in containers (by name or ID prefix), and
+--group-by-cgroup to group the trees by cgroup; both read /proc only and work on Linux.
+Use --user, --uid and --group to only keep matches owned by those users and groups; on their own
+they select all processes of those owners.
+Use the --kill/-k flag to send signals to matching processes after displaying trees, and add --kill-tree
+to signal their whole subtrees too, root first or with --kill-order leaves; processes are checked
+against their start time first, so a PID reused since the tree was read is never signaled.
+Use --kill-grace to wait for the signaled processes to exit, showing a countdown and the ones still
+running, then send SIGKILL to them and report the outcome for each PID.
+In a terminal, watch mode is interactive: move with the arrow keys (or j/k), collapse and expand
+subtrees with left/right or space, press s to signal the selected process, / to change the targets,
++/- to change the refresh interval, r to refresh and q to quit.
+Use --output/-o json to print the trees as a JSON array of {"target", "tree"} objects.
+
+Output format: PID USER CPU% Memory CommandLine (change it with --columns or --format)
 Memory usage is shown in human-readable format (KB/MB/GB). Processes are highlighted in green.`
 
 // NewApp builds the CLI application configuration.
 func NewApp() *cli.App {
 	app := &cli.App{
 		Name:      "psjungle",
-		Usage:     "Display process trees for PIDs, ports, or patterns (regex by default, strict string with -s flag)",
+		Usage:     "Display process trees for PIDs, ports, open files, systemd units, PID namespaces, or patterns (regex by default, strict string with -s flag)",
 		UsageText: appUsageText,
 		Flags:     defineFlags(),
 		Action: func(c *cli.Context) error {
-			flatMode := c.Bool("flat")
-			strictMode := c.Bool("strict")
+			opts, err := parseOptions(c)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
 
 			// Get all inputs (all non-flag arguments)
 			inputs := make([]string, c.NArg())
@@ -773,20 +1162,19 @@ func NewApp() *cli.App {
 				inputs[i] = c.Args().Get(i)
 			}
 
-			host := c.String("host")
 			killValue := c.String("kill")
 
 			// Check if watch flag was explicitly set
 			if c.IsSet("watch") {
 				// Validate arguments for watch mode
-				if c.NArg() < 1 {
+				if c.NArg() < 1 && !opts.selectsWithoutInputs() {
 					cli.ShowAppHelp(c)
 					return cli.Exit("Watch mode requires at least one target PID/port/name", 1)
 				}
-				return handleWatchMode(c, inputs, flatMode, strictMode, host, killValue)
+				return handleWatchMode(c, inputs, opts, killValue)
 			}
 
-			return handleNormalMode(c, inputs, flatMode, strictMode, host, killValue)
+			return handleNormalMode(c, inputs, opts, killValue)
 		},
 	}
 
@@ -794,7 +1182,7 @@ func NewApp() *cli.App {
 }
 
 // handleWatchMode processes the watch mode functionality
-func handleWatchMode(c *cli.Context, inputs []string, flatMode bool, strictMode bool, host string, killValue string) error {
+func handleWatchMode(c *cli.Context, inputs []string, opts *options, killValue string) error {
 	watchValue := c.String("watch")
 
 	// Parse watch interval from flag value
@@ -820,110 +1208,162 @@ func handleWatchMode(c *cli.Context, inputs []string, flatMode bool, strictMode
 		useKill = true
 	}
 
+	// Use the interactive view when attached to a terminal
+	if !opts.jsonOutput() && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		interval := time.Duration(watchInterval) * time.Second
+		return runWatchUI(inputs, opts, interval, killSignal, useKill, killValue)
+	}
+
 	for {
-		// Clear screen
-		fmt.Print("\033[H\033[2J")
-		// Print status line with all targets
-		fmt.Printf("Every %.1fs: psjungle -w%s", float64(watchInterval), watchValue)
-		if strictMode {
-			fmt.Print(" -s")
-		}
-		if host != "" {
-			fmt.Printf(" --host %s", host)
-		}
-		if useKill {
-			if killValue == "" {
-				fmt.Print(" -k")
-			} else {
-				fmt.Printf(" -k=%s", killValue)
-			}
-		}
-		for _, input := range inputs {
-			fmt.Printf(" %s", input)
+		// JSON output is streamed one document per refresh, without the screen header
+		if !opts.jsonOutput() {
+			// Clear screen
+			fmt.Print("\033[H\033[2J")
+			// Print status line with all targets
+			fmt.Printf("Every %.1fs: psjungle -w%s%s", float64(watchInterval), watchValue, watchCommandArgs(opts, inputs, useKill, killValue))
+			fmt.Println()
+			fmt.Println()
 		}
-		fmt.Println()
-		fmt.Println()
 		// Run pstree and get the list of processed PIDs
-		processedPids, err := runPstree(inputs, flatMode, strictMode, host)
-		if err != nil {
+		// Keep watching when every target is gone: they may come back, like in the interactive view
+		trees, processedPids, err := runPstree(inputs, opts)
+		if err != nil && !errors.Is(err, errNoProcesses) {
 			return cli.Exit(err.Error(), 1)
 		}
 
 		// If kill flag is set, send signal to processed PIDs
 		if useKill {
-			// Send signal to all processed PIDs
-			for _, pid := range processedPids {
-				proc, err := process.NewProcess(int32(pid))
-				if err != nil {
-					fmt.Printf("Warning: Could not create process object for PID %d: %v\n", pid, err)
-					continue
-				}
-
-				// Send the signal
-				if err := proc.SendSignal(killSignal); err != nil {
-					fmt.Printf("Warning: Could not send signal to PID %d: %v\n", pid, err)
-				} else {
-					fmt.Printf("Sent signal %d to PID %d\n", killSignal, pid)
-				}
-			}
+			signalMatches(trees, processedPids, killSignal, opts, opts.messages())
 		}
 		time.Sleep(time.Duration(watchInterval) * time.Second)
 	}
 }
 
+// watchCommandArgs formats the flags and targets shown in the watch status line
+func watchCommandArgs(opts *options, inputs []string, useKill bool, killValue string) string {
+	var b strings.Builder
+	if opts.strictMode {
+		b.WriteString(" -s")
+	}
+	if q := opts.portQuery; q.Host != "" {
+		fmt.Fprintf(&b, " --host %s", q.Host)
+	}
+	if q := opts.portQuery; q.Proto != "" {
+		fmt.Fprintf(&b, " --proto %s", q.Proto)
+	}
+	if q := opts.portQuery; q.State != "" {
+		fmt.Fprintf(&b, " --state %s", strings.ToLower(q.State))
+	}
+	if opts.portQuery.Remote {
+		b.WriteString(" --remote")
+	}
+	if opts.owner != nil {
+		b.WriteString(opts.owner.args)
+	}
+	if opts.container != nil {
+		b.WriteString(opts.container.args)
+	}
+	if opts.where != nil {
+		fmt.Fprintf(&b, " --where %q", opts.where)
+	}
+	if opts.top != nil {
+		fmt.Fprintf(&b, " --top %d --by %s", opts.top.n, opts.top.by)
+	}
+	if useKill {
+		if killValue == "" {
+			b.WriteString(" -k")
+		} else {
+			fmt.Fprintf(&b, " -k=%s", killValue)
+		}
+	}
+	if opts.killTree {
+		fmt.Fprintf(&b, " --kill-tree --kill-order %s", opts.killOrder)
+	}
+	for _, input := range inputs {
+		fmt.Fprintf(&b, " %s", input)
+	}
+	return b.String()
+}
+
 // handleNormalMode processes the normal (non-watch) mode functionality
-func handleNormalMode(c *cli.Context, inputs []string, flatMode bool, strictMode bool, host string, killValue string) error {
-	// If we get here and have no arguments, show help
-	if c.NArg() < 1 {
+func handleNormalMode(c *cli.Context, inputs []string, opts *options, killValue string) error {
+	// If we get here and have no arguments or filters, show help
+	if c.NArg() < 1 && !opts.selectsWithoutInputs() {
 		cli.ShowAppHelp(c)
 		return cli.Exit("", 1)
 	}
 
 	// Run pstree and get the list of processed PIDs
-	processedPids, err := runPstree(inputs, flatMode, strictMode, host)
+	trees, processedPids, err := runPstree(inputs, opts)
+	if errors.Is(err, errNoProcesses) {
+		return cli.Exit("", 1)
+	}
 	if err != nil {
 		return cli.Exit(err.Error(), 1)
 	}
 
-	// If kill flag is set, send signal to processed PIDs
-	if c.IsSet("kill") {
+	// If kill flag is set, send signal to processed PIDs; --kill-grace implies it
+	if c.IsSet("kill") || opts.killGrace > 0 {
 		signal, err := parseSignal(killValue)
 		if err != nil {
 			return cli.Exit(fmt.Sprintf("Error parsing signal: %v", err), 1)
 		}
 
-		// Send signal to all processed PIDs
-		for _, pid := range processedPids {
-			proc, err := process.NewProcess(int32(pid))
-			if err != nil {
-				fmt.Printf("Warning: Could not create process object for PID %d: %v\n", pid, err)
-				continue
-			}
-
-			// Send the signal
-			if err := proc.SendSignal(signal); err != nil {
-				fmt.Printf("Warning: Could not send signal to PID %d: %v\n", pid, err)
-			} else {
-				fmt.Printf("Sent signal %d to PID %d\n", signal, pid)
+		// Escalate to SIGKILL for the processes that outlive the grace period
+		if opts.killGrace > 0 {
+			if !killGracefully(killTargets(trees, opts), signal, opts) {
+				return cli.Exit("Some processes are still running", 1)
 			}
+			return nil
 		}
+		signalMatches(trees, processedPids, signal, opts, opts.messages())
 	}
 
 	return nil
 }
 
+// signalPids sends the signal to all PIDs and reports the outcome to w
+func signalPids(pids []int, signal syscall.Signal, w io.Writer) {
+	for _, pid := range pids {
+		proc, err := process.NewProcess(int32(pid))
+		if err != nil {
+			fmt.Fprintf(w, "Warning: Could not create process object for PID %d: %v\n", pid, err)
+			continue
+		}
+
+		// Send the signal
+		if err := proc.SendSignal(signal); err != nil {
+			fmt.Fprintf(w, "Warning: Could not send signal to PID %d: %v\n", pid, err)
+		} else {
+			fmt.Fprintf(w, "Sent signal %d to PID %d\n", signal, pid)
+		}
+	}
+}
+
 // displayProcessTrees shows process trees for all PIDs, avoiding duplicates
-// Returns the list of PIDs that were processed (had trees displayed)
-func displayProcessTrees(allPids []int, flatMode bool, shownPids map[int]bool) ([]int, error) {
-	// Display process trees for all PIDs, but avoid duplicates
-	// Keep track of which PIDs we actually displayed trees for
+// Returns the trees and the list of PIDs that were processed (had trees displayed)
+func displayProcessTrees(allPids []int, opts *options, shownPids map[int]bool) ([]*targetTree, []int, error) {
+	trees, processedPids := collectTrees(allPids, opts, opts.messages(), shownPids)
+
+	if err := renderTrees(trees, len(allPids) > 1, opts); err != nil {
+		return nil, nil, err
+	}
+
+	return trees, processedPids, nil
+}
+
+// collectTrees builds the focused trees for all PIDs, skipping PIDs whose tree was already collected.
+// Problems with individual PIDs are reported to w.
+// Returns the trees and the list of PIDs that were processed.
+func collectTrees(allPids []int, opts *options, w io.Writer, shownPids map[int]bool) ([]*targetTree, []int) {
+	// Keep track of which PIDs we actually built trees for
 	var processedPids []int
-	firstTree := true
+	var trees []*targetTree
 	for _, pid := range allPids {
 		// Skip if process doesn't exist
 		_, err := process.NewProcess(int32(pid))
 		if err != nil {
-			fmt.Printf("Process %d not found\n", pid)
+			fmt.Fprintf(w, "Process %d not found\n", pid)
 			continue
 		}
 
@@ -939,16 +1379,13 @@ func displayProcessTrees(allPids []int, flatMode bool, shownPids map[int]bool) (
 			}
 		}
 
-		// If this tree hasn't been shown yet, display it
+		// If this tree hasn't been shown yet, build it
 		if !alreadyShown {
-			if !firstTree {
-				fmt.Println()
-			}
-			if len(allPids) > 1 {
-				fmt.Printf("Process tree for PID %d:\n", pid)
-			}
-			if err := pstreeBoth(pid, flatMode); err != nil {
-				fmt.Printf("Error for PID %d: %v\n", pid, err)
+			tree, err := pstreeBoth(pid)
+			if err != nil {
+				fmt.Fprintf(w, "Error for PID %d: %v\n", pid, err)
+			} else {
+				trees = append(trees, &targetTree{pid: pid, root: tree})
 			}
 
 			// Add the target PID to our processed list
@@ -958,12 +1395,40 @@ func displayProcessTrees(allPids []int, flatMode bool, shownPids map[int]bool) (
 			for _, treePid := range treePids {
 				shownPids[treePid] = true
 			}
-
-			firstTree = false
 		}
 	}
 
-	return processedPids, nil
+	// Threads are sampled together with their processes
+	if opts.threads {
+		attachThreads(trees)
+	}
+	// CPU% has to be known before children can be ordered by it
+	opts.cpu.apply(trees)
+	if opts.sockets {
+		attachSockets(trees, w)
+	}
+	if opts.files {
+		attachFiles(trees)
+	}
+	if opts.proportional {
+		attachProportionalMemory(trees)
+	}
+	if opts.cgroups {
+		attachCgroups(trees)
+	}
+	if opts.namespaces {
+		attachNamespaces(trees)
+	}
+	if opts.aggregate {
+		attachTotals(trees, opts.aggregateMem == aggregatePSS)
+	}
+	// Exited processes are put back into the trees before sorting, so they keep their place
+	opts.changes.update(trees)
+	for _, t := range trees {
+		sortTree(t.root, opts.sortKey, opts.reverse)
+	}
+
+	return trees, processedPids
 }
 
 // Run executes the CLI application with provided args.
@@ -0,0 +1,354 @@
+package psjungle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// containerInfo identifies the container a process runs in. It is derived from the
+// cgroup path alone, and the name from the runtime's state on disk: no daemon is called.
+type containerInfo struct {
+	Runtime string `json:"runtime"` // docker, containerd, cri-o or podman
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+}
+
+// shortID is the container ID abbreviated like docker ps
+func (c containerInfo) shortID() string {
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
+
+// String formats the container for the container column, e.g. "docker:web" or
+// "containerd:3f2a9c1b7d4e" when the name is unknown
+func (c containerInfo) String() string {
+	if c.Name != "" {
+		return c.Runtime + ":" + c.Name
+	}
+	return c.Runtime + ":" + c.shortID()
+}
+
+// containerScopes maps the prefixes of the systemd scopes created by container
+// runtimes to the runtime, e.g. docker-<id>.scope
+var containerScopes = []struct {
+	prefix  string
+	runtime string
+}{
+	{"docker-", "docker"},
+	{"cri-containerd-", "containerd"},
+	{"nerdctl-", "containerd"},
+	{"crio-", "cri-o"},
+	{"libpod-", "podman"},
+}
+
+// containerIDPattern matches the 64 hex digit IDs used by all supported runtimes
+var containerIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// parseContainer finds the container in a cgroup path. The innermost container wins,
+// e.g. /system.slice/docker-<id>.scope or /kubepods/burstable/pod<uid>/<id>.
+func parseContainer(path string) (containerInfo, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := segments[i]
+
+		// Scopes created with the systemd cgroup driver
+		if id, ok := strings.CutSuffix(segment, ".scope"); ok {
+			for _, scope := range containerScopes {
+				if rest, ok := strings.CutPrefix(id, scope.prefix); ok && containerIDPattern.MatchString(rest) {
+					return containerInfo{Runtime: scope.runtime, ID: rest}, true
+				}
+			}
+			continue
+		}
+
+		// Plain IDs created with the cgroupfs driver, named after their parent:
+		// /docker/<id>, /libpod_parent/libpod-<id>, or a Kubernetes pod or containerd namespace
+		id := strings.TrimPrefix(segment, "libpod-")
+		if !containerIDPattern.MatchString(id) {
+			continue
+		}
+		runtime := "containerd"
+		if i > 0 {
+			switch parent := segments[i-1]; {
+			case parent == "docker":
+				runtime = "docker"
+			case parent == "libpod_parent" || strings.HasPrefix(segment, "libpod-"):
+				runtime = "podman"
+			}
+		}
+		return containerInfo{Runtime: runtime, ID: id}, true
+	}
+	return containerInfo{}, false
+}
+
+// unitSuffixes are the types of systemd units that hold processes in their own cgroup
+var unitSuffixes = []string{".service", ".scope"}
+
+// parseUnit finds the systemd unit and slice owning a cgroup path: the innermost service
+// or scope, and the innermost slice containing it. For example
+// /user.slice/user-1000.slice/user@1000.service/app.slice/foo.service is foo.service in app.slice.
+// Sub-cgroups created by a service, such as podman's container cgroup, belong to that service.
+func parseUnit(path string) (unit, slice string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	// Without a unit, the slice is searched from the innermost cgroup
+	i := len(segments) - 1
+	for j := len(segments) - 1; j >= 0 && unit == ""; j-- {
+		for _, suffix := range unitSuffixes {
+			if strings.HasSuffix(segments[j], suffix) {
+				unit, i = segments[j], j-1
+			}
+		}
+	}
+
+	for ; i >= 0; i-- {
+		if strings.HasSuffix(segments[i], ".slice") {
+			return unit, segments[i]
+		}
+	}
+	// Units in the root cgroup, such as init.scope, have no slice
+	return unit, ""
+}
+
+// unitName completes a unit name given without a type, like systemctl: nginx is nginx.service.
+// Patterns are completed as well, php*-fpm is php*-fpm.service, unless they end in * and
+// already match every type.
+func unitName(name string) string {
+	for _, suffix := range append(unitSuffixes, ".slice") {
+		if strings.HasSuffix(name, suffix) {
+			return name
+		}
+	}
+	if strings.HasSuffix(name, "*") {
+		return name
+	}
+	return name + ".service"
+}
+
+// containerNames caches the names found for container IDs, including unknown ones
+var containerNames = make(map[string]string)
+
+// lookupContainerName reads the name of a container from the runtime's files on disk.
+// They are usually only readable by root, so the name may stay unknown.
+func lookupContainerName(c containerInfo) string {
+	if name, ok := containerNames[c.ID]; ok {
+		return name
+	}
+
+	var name string
+	switch c.Runtime {
+	case "docker":
+		name = dockerContainerName(c.ID)
+	case "podman":
+		name = podmanContainerName(c.ID)
+	case "containerd":
+		name = containerdContainerName(c.ID)
+	}
+	containerNames[c.ID] = name
+	return name
+}
+
+// dockerContainerName reads the name from /var/lib/docker/containers/<id>/config.v2.json
+func dockerContainerName(id string) string {
+	var config struct {
+		Name string
+	}
+	if !readJSONFile(filepath.Join("/var/lib/docker/containers", id, "config.v2.json"), &config) {
+		return ""
+	}
+	return strings.TrimPrefix(config.Name, "/")
+}
+
+// podmanContainerName reads the name from the containers.json of the system-wide
+// storage, or of the current user's storage for rootless containers
+func podmanContainerName(id string) string {
+	paths := []string{"/var/lib/containers/storage/overlay-containers/containers.json"}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".local/share/containers/storage/overlay-containers/containers.json"))
+	}
+
+	for _, path := range paths {
+		var containers []struct {
+			ID    string   `json:"id"`
+			Names []string `json:"names"`
+		}
+		if !readJSONFile(path, &containers) {
+			continue
+		}
+		for _, c := range containers {
+			if c.ID == id && len(c.Names) > 0 {
+				return c.Names[0]
+			}
+		}
+	}
+	return ""
+}
+
+// containerdContainerName reads the name from the annotations in the OCI bundle of a
+// containerd task: the pod and container names for Kubernetes, or the nerdctl name
+func containerdContainerName(id string) string {
+	bundles, _ := filepath.Glob(filepath.Join("/run/containerd/io.containerd.runtime.v2.task/*", id, "config.json"))
+	for _, bundle := range bundles {
+		var config struct {
+			Annotations map[string]string `json:"annotations"`
+		}
+		if !readJSONFile(bundle, &config) {
+			continue
+		}
+		if container := config.Annotations["io.kubernetes.cri.container-name"]; container != "" {
+			if pod := config.Annotations["io.kubernetes.cri.sandbox-name"]; pod != "" {
+				return pod + "/" + container
+			}
+			return container
+		}
+		if name := config.Annotations["nerdctl/name"]; name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// readJSONFile decodes a JSON file into v, reporting whether it could be read
+func readJSONFile(path string, v any) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// processContainer returns the container of a process with its name, if it runs in one
+func processContainer(pid int32) (containerInfo, bool) {
+	c, ok := parseContainer(processCgroup(pid))
+	if ok {
+		c.Name = lookupContainerName(c)
+	}
+	return c, ok
+}
+
+// cgroupColumns are the columns that need the cgroup of a process
+var cgroupColumns = []string{"cgroup", "container", "unit", "slice"}
+
+// needsCgroups reports whether the cgroup, container and unit of every process have to be
+// read: for JSON, --group-by-cgroup, or the selected columns or --format template.
+// Looking up container names reads the runtime's state, so it is skipped otherwise.
+func needsCgroups(opts *options, formatText string) bool {
+	if opts.jsonOutput() || opts.byCgroup || showsColumn(opts, cgroupColumns...) {
+		return true
+	}
+	for _, field := range []string{".Cgroup", ".Container", ".Unit", ".Slice"} {
+		if strings.Contains(formatText, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachCgroups reads the cgroup, container and unit of every node in the trees
+func attachCgroups(trees []*targetTree) {
+	for _, t := range trees {
+		for _, node := range appendNodes(nil, t.root) {
+			getProcessInfo(node)
+			info := node.info
+			info.Cgroup = processCgroup(node.Process.Pid)
+			if c, ok := parseContainer(info.Cgroup); ok {
+				c.Name = lookupContainerName(c)
+				info.Container = &c
+			}
+			info.Unit, info.Slice = parseUnit(info.Cgroup)
+		}
+	}
+}
+
+// cgroupLabel describes the cgroup of a process for group headers, naming its container
+// or systemd unit when it has one
+func cgroupLabel(info processInfo) string {
+	if info.Cgroup == "" {
+		return "unknown cgroup"
+	}
+	if c := info.Container; c != nil && c.Name != "" {
+		return fmt.Sprintf("%s container %s (%s), cgroup %s", c.Runtime, c.Name, c.shortID(), info.Cgroup)
+	} else if c != nil {
+		return fmt.Sprintf("%s container %s, cgroup %s", c.Runtime, c.shortID(), info.Cgroup)
+	}
+	if info.Unit != "" {
+		return fmt.Sprintf("unit %s, cgroup %s", info.Unit, info.Cgroup)
+	}
+	return "cgroup " + info.Cgroup
+}
+
+// containerFilter narrows the matched processes to the given containers, by name
+// or by ID prefix like the docker CLI
+type containerFilter struct {
+	values []string
+
+	// args are the flags the filter was built from, for the watch status line
+	args string
+}
+
+// parseContainerFilter builds the filter from the comma-separated --container value.
+// It returns nil when it is empty.
+func parseContainerFilter(value string) (*containerFilter, error) {
+	values := splitList(value)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if !cgroupsSupported {
+		return nil, fmt.Errorf("--container is only supported on Linux")
+	}
+	return &containerFilter{values: values, args: " --container " + value}, nil
+}
+
+// Match reports whether the process runs in one of the containers
+func (f *containerFilter) Match(proc *process.Process) bool {
+	c, ok := processContainer(proc.Pid)
+	return ok && f.matches(c)
+}
+
+// matches reports whether the container is one of the filter's, by name or ID prefix
+func (f *containerFilter) matches(c containerInfo) bool {
+	for _, value := range f.values {
+		if value == c.Name || strings.HasPrefix(c.ID, strings.ToLower(value)) {
+			return true
+		}
+		// Kubernetes containers are named pod/container; the pod name alone selects the whole pod
+		if pod, _, found := strings.Cut(c.Name, "/"); found && value == pod {
+			return true
+		}
+	}
+	return false
+}
+
+// treeGroup is a set of trees whose targets share a cgroup, for --group-by-cgroup
+type treeGroup struct {
+	label string
+	trees []*targetTree
+}
+
+// groupTrees groups the trees by the cgroup of their target, in order of first appearance
+func groupTrees(trees []*targetTree) []*treeGroup {
+	var groups []*treeGroup
+	byLabel := make(map[string]*treeGroup)
+	for _, t := range trees {
+		label := "unknown cgroup"
+		if target := findTargetNode(t.root, t.pid); target != nil {
+			label = cgroupLabel(getProcessInfo(target))
+		}
+		group, ok := byLabel[label]
+		if !ok {
+			group = &treeGroup{label: label}
+			byLabel[label] = group
+			groups = append(groups, group)
+		}
+		group.trees = append(group.trees, t)
+	}
+	return groups
+}
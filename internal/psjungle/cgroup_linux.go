@@ -0,0 +1,50 @@
+package psjungle
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cgroupsSupported reports whether cgroups can be read on this platform
+const cgroupsSupported = true
+
+// processCgroup returns the cgroup path of a process from /proc/<pid>/cgroup.
+// With cgroup v2 there is a single "0::<path>" line. With v1 or hybrid hierarchies
+// there is one line per controller: the first path naming a container is used, then
+// the unified or systemd hierarchy.
+func processCgroup(pid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+
+	var unified, systemd, first string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// hierarchy-ID:controllers:path
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		path := fields[2]
+		if _, ok := parseContainer(path); ok {
+			return path
+		}
+		switch {
+		case fields[0] == "0" && fields[1] == "":
+			unified = path
+		case fields[1] == "name=systemd":
+			systemd = path
+		case first == "" && path != "/":
+			first = path
+		}
+	}
+
+	// Hybrid hierarchies leave the unified path at the root
+	for _, path := range []string{unified, systemd, first} {
+		if path != "" && path != "/" {
+			return path
+		}
+	}
+	return unified
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package psjungle
+
+// cgroupsSupported reports whether cgroups can be read on this platform
+const cgroupsSupported = false
+
+// processCgroup is not available on this platform
+func processCgroup(pid int32) string {
+	return ""
+}
@@ -0,0 +1,145 @@
+package psjungle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnit(t *testing.T) {
+	tests := []struct {
+		path  string
+		unit  string
+		slice string
+	}{
+		{"/system.slice/nginx.service", "nginx.service", "system.slice"},
+		{"/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service", "foo.service", "app.slice"},
+		{"/user.slice/user-1000.slice/session-2.scope", "session-2.scope", "user-1000.slice"},
+		{"/system.slice/system-getty.slice/getty@tty1.service", "getty@tty1.service", "system-getty.slice"},
+		// Sub-cgroups created by a service belong to it
+		{"/system.slice/podman.service/libpod-payload", "podman.service", "system.slice"},
+		{"/user.slice/user-1000.slice", "", "user-1000.slice"},
+		{"/init.scope", "init.scope", ""},
+		{"/", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			unit, slice := parseUnit(tt.path)
+			if unit != tt.unit || slice != tt.slice {
+				t.Errorf("parseUnit(%q) = %q, %q, want %q, %q", tt.path, unit, slice, tt.unit, tt.slice)
+			}
+		})
+	}
+}
+
+func TestUnitName(t *testing.T) {
+	tests := map[string]string{
+		"nginx":           "nginx.service",
+		"nginx.service":   "nginx.service",
+		"session-2.scope": "session-2.scope",
+		"user-1000.slice": "user-1000.slice",
+		"php*-fpm":        "php*-fpm.service",
+		"nginx*":          "nginx*",
+	}
+	for name, want := range tests {
+		if got := unitName(name); got != want {
+			t.Errorf("unitName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestUnitMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"nginx", "/system.slice/nginx.service", true},
+		{"nginx", "/system.slice/nginx-exporter.service", false},
+		{"php*-fpm", "/system.slice/php8.2-fpm.service", true},
+		{"foo", "/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service", true},
+		{"session-2.scope", "/user.slice/user-1000.slice/session-2.scope", true},
+		// Slices match every process below them, at any depth
+		{"user-1000.slice", "/user.slice/user-1000.slice/user@1000.service/app.slice/foo.service", true},
+		{"system.slice", "/user.slice/user-1000.slice/session-2.scope", false},
+		{"init", "/init.scope", false},
+		{"nginx", "/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.path, func(t *testing.T) {
+			if got := unitMatches(unitName(tt.name), tt.path); got != tt.want {
+				t.Errorf("unit:%s matches %s = %v, want %v", tt.name, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseContainer(t *testing.T) {
+	const id = "3f2a9c1b7d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8"
+	tests := []struct {
+		name    string
+		path    string
+		runtime string // empty when the path is not in a container
+	}{
+		{"docker scope", "/system.slice/docker-" + id + ".scope", "docker"},
+		{"docker cgroupfs", "/docker/" + id, "docker"},
+		{"containerd scope", "/system.slice/cri-containerd-" + id + ".scope", "containerd"},
+		{"nerdctl scope", "/system.slice/nerdctl-" + id + ".scope", "containerd"},
+		{"cri-o scope", "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234.slice/crio-" + id + ".scope", "cri-o"},
+		{"podman scope", "/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + id + ".scope", "podman"},
+		{"podman cgroupfs", "/libpod_parent/libpod-" + id, "podman"},
+		{"kubepods burstable", "/kubepods/burstable/pod0c1d2e3f-4a5b-6c7d-8e9f-0a1b2c3d4e5f/" + id, "containerd"},
+		{"kubepods besteffort", "/kubepods/besteffort/pod0c1d2e3f-4a5b-6c7d-8e9f-0a1b2c3d4e5f/" + id, "containerd"},
+		{"kubepods systemd driver", "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod1234.slice/cri-containerd-" + id + ".scope", "containerd"},
+
+		{"service", "/system.slice/nginx.service", ""},
+		{"session scope", "/user.slice/user-1000.slice/session-2.scope", ""},
+		{"short hex ID", "/docker/3f2a9c1b7d4e", ""},
+		{"short hex scope", "/system.slice/docker-3f2a9c1b7d4e.scope", ""},
+		{"uppercase hex ID", "/docker/" + strings.ToUpper(id), ""},
+		{"pod without container", "/kubepods/burstable/pod0c1d2e3f-4a5b-6c7d-8e9f-0a1b2c3d4e5f", ""},
+		{"root", "/", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := parseContainer(tt.path)
+			if tt.runtime == "" {
+				if ok {
+					t.Errorf("parseContainer(%q) = %+v, want no container", tt.path, c)
+				}
+				return
+			}
+			if !ok || c.Runtime != tt.runtime || c.ID != id {
+				t.Errorf("parseContainer(%q) = %+v, %v, want %s container %s", tt.path, c, ok, tt.runtime, id)
+			}
+		})
+	}
+}
+
+func TestContainerFilterMatches(t *testing.T) {
+	web := containerInfo{Runtime: "docker", ID: "3f2a9c1b7d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8", Name: "web"}
+	pod := containerInfo{Runtime: "containerd", ID: "8b1e02d4c6a9f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8", Name: "api-7d9f/server"}
+	tests := []struct {
+		value string
+		c     containerInfo
+		want  bool
+	}{
+		{"web", web, true},
+		{"3f2a9c", web, true},
+		{"3F2A9C", web, true},
+		{"we", web, false},
+		{"8b1e02", web, false},
+		{"api-7d9f/server", pod, true},
+		{"api-7d9f", pod, true},
+		{"server", pod, false},
+		{"db,web", web, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			f := &containerFilter{values: splitList(tt.value)}
+			if got := f.matches(tt.c); got != tt.want {
+				t.Errorf("--container %s matches %s = %v, want %v", tt.value, tt.c, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package psjungle
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Thresholds for highlighting a process whose resource usage changed sharply
+// between two watch refreshes
+const (
+	cpuSwingPoints   = 20.0     // CPU% points
+	rssSwingRatio    = 0.25     // fraction of the previous RSS
+	rssSwingMinBytes = 10 << 20 // ignore small processes growing from a few KB
+)
+
+// processKey identifies a process across refreshes. The start time guards
+// against a PID being reused by a different process.
+type processKey struct {
+	pid   int32
+	start time.Time
+}
+
+// keyOf returns the identity of the process shown by a node
+func keyOf(node *ProcessNode) processKey {
+	info := getProcessInfo(node)
+	return processKey{pid: info.Pid, start: info.Start}
+}
+
+// changeTracker remembers the processes shown by the previous watch refresh so
+// the next refresh can highlight what changed
+type changeTracker struct {
+	previous map[processKey]processInfo
+	parents  map[processKey]processKey
+	primed   bool
+
+	// exited holds the processes that disappeared in the latest refresh and
+	// could not be placed back under their parent
+	exited []processInfo
+}
+
+// newChangeTracker creates a tracker with no previous refresh
+func newChangeTracker() *changeTracker {
+	return &changeTracker{
+		previous: make(map[processKey]processInfo),
+		parents:  make(map[processKey]processKey),
+	}
+}
+
+// update compares the trees with the previous refresh. It marks processes that
+// appeared or whose CPU/RSS swung sharply, and puts processes that exited back
+// under their parent as greyed-out nodes for one refresh. The first refresh marks nothing.
+func (t *changeTracker) update(trees []*targetTree) {
+	if t == nil {
+		return
+	}
+
+	current := make(map[processKey]processInfo)
+	parents := make(map[processKey]processKey)
+	nodes := make(map[processKey]*ProcessNode)
+	for _, tree := range trees {
+		for _, node := range appendNodes(nil, tree.root) {
+			key := keyOf(node)
+			info := getProcessInfo(node)
+			current[key] = info
+			nodes[key] = node
+			if node.Parent != nil {
+				parents[key] = keyOf(node.Parent)
+			}
+
+			if !t.primed {
+				continue
+			}
+			if prev, ok := t.previous[key]; !ok {
+				node.appeared = true
+			} else if swung(prev, info) {
+				node.swung = true
+			}
+		}
+	}
+
+	// Place exited processes in start order, so a parent that exited together
+	// with its children is back in the tree before them
+	var exitedKeys []processKey
+	for key := range t.previous {
+		if _, ok := current[key]; !ok {
+			exitedKeys = append(exitedKeys, key)
+		}
+	}
+	sort.Slice(exitedKeys, func(i, j int) bool {
+		if !exitedKeys[i].start.Equal(exitedKeys[j].start) {
+			return exitedKeys[i].start.Before(exitedKeys[j].start)
+		}
+		return exitedKeys[i].pid < exitedKeys[j].pid
+	})
+
+	t.exited = t.exited[:0]
+	for _, key := range exitedKeys {
+		info := t.previous[key]
+		info.IsTarget = false
+		info.Total = nil
+		info.Tasks = nil
+
+		parentKey, hasParent := t.parents[key]
+		parent, parentShown := nodes[parentKey]
+		if !hasParent || !parentShown {
+			t.exited = append(t.exited, info)
+			continue
+		}
+
+		ghost := &ProcessNode{
+			Process:  &process.Process{Pid: key.pid},
+			Children: []*ProcessNode{},
+			Depth:    parent.Depth + 1,
+			IsTarget: false,
+			Parent:   parent,
+			info:     &info,
+			exited:   true,
+		}
+		parent.Children = append(parent.Children, ghost)
+		nodes[key] = ghost
+	}
+
+	t.previous = current
+	t.parents = parents
+	t.primed = true
+}
+
+// swung reports whether CPU% or RSS changed sharply between two refreshes
+func swung(prev, cur processInfo) bool {
+	if math.Abs(cur.CPU-prev.CPU) >= cpuSwingPoints {
+		return true
+	}
+	delta := math.Abs(float64(cur.RSS) - float64(prev.RSS))
+	return delta >= rssSwingMinBytes && delta >= rssSwingRatio*float64(prev.RSS)
+}
+
+// reset forgets the previous refresh, e.g. after the watched targets changed
+func (t *changeTracker) reset() {
+	if t == nil {
+		return
+	}
+	t.previous = make(map[processKey]processInfo)
+	t.parents = make(map[processKey]processKey)
+	t.primed = false
+}
+
+// enabled reports whether changes are being tracked (watch mode)
+func (t *changeTracker) enabled() bool {
+	return t != nil
+}
+
+// changeMarker returns the gutter shown before a tree line in watch mode
+func changeMarker(node *ProcessNode) string {
+	switch {
+	case node.appeared:
+		return "+ "
+	case node.exited:
+		return "- "
+	default:
+		return "  "
+	}
+}
+
+// nodeStyle returns the ANSI style for a tree line: exited processes are greyed
+// out, targets are green, new processes cyan, and sharp CPU/RSS swings bold
+func nodeStyle(node *ProcessNode) string {
+	style := ""
+	switch {
+	case node.exited:
+		style = "\033[90m"
+	case node.IsTarget:
+		style = "\033[32m"
+	case node.appeared:
+		style = "\033[36m"
+	}
+	if node.swung {
+		style += "\033[1m"
+	}
+	return style
+}
+
+// formatExitedLine formats a process that disappeared since the previous refresh
+func formatExitedLine(info processInfo, opts *options) string {
+	return fmt.Sprintf("- %s", formatNodeLine(info, opts))
+}
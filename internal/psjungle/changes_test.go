@@ -0,0 +1,199 @@
+package psjungle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testStart is the start time of the processes built by the change tracker tests
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// startedNode builds a node for a process that started offset seconds after testStart
+func startedNode(pid int32, offset int, children ...*ProcessNode) *ProcessNode {
+	return testNode(processInfo{Pid: pid, Start: testStart.Add(time.Duration(offset) * time.Second)}, children...)
+}
+
+// findTestNode returns the node of pid in the tree, or nil
+func findTestNode(node *ProcessNode, pid int32) *ProcessNode {
+	for _, n := range appendNodes(nil, node) {
+		if n.Process.Pid == pid {
+			return n
+		}
+	}
+	return nil
+}
+
+func TestChangeTrackerMarksAppeared(t *testing.T) {
+	tracker := newChangeTracker()
+
+	// 1
+	// └── 2
+	first := startedNode(1, 0, startedNode(2, 1))
+	tracker.update([]*targetTree{{pid: 1, root: first}})
+	for _, node := range appendNodes(nil, first) {
+		if node.appeared || node.swung {
+			t.Errorf("PID %d marked on the first refresh", node.Process.Pid)
+		}
+	}
+
+	// 1
+	// ├── 2
+	// └── 3
+	second := startedNode(1, 0, startedNode(2, 1), startedNode(3, 2))
+	tracker.update([]*targetTree{{pid: 1, root: second}})
+	for _, node := range appendNodes(nil, second) {
+		want := node.Process.Pid == 3
+		if node.appeared != want {
+			t.Errorf("PID %d appeared = %v, want %v", node.Process.Pid, node.appeared, want)
+		}
+	}
+}
+
+func TestChangeTrackerReusedPidAppears(t *testing.T) {
+	tracker := newChangeTracker()
+	tracker.update([]*targetTree{{pid: 1, root: startedNode(1, 0, startedNode(2, 1))}})
+
+	// PID 2 now belongs to a process with another start time
+	root := startedNode(1, 0, startedNode(2, 5))
+	tracker.update([]*targetTree{{pid: 1, root: root}})
+
+	if pids := childPids(root); !reflect.DeepEqual(pids, []int32{2, 2}) {
+		t.Fatalf("children of PID 1 = %v, want the new and the exited PID 2", pids)
+	}
+	if !root.Children[0].appeared || root.Children[0].exited {
+		t.Errorf("new PID 2 should be marked as appeared")
+	}
+	if !root.Children[1].exited {
+		t.Errorf("old PID 2 should be marked as exited")
+	}
+}
+
+func TestChangeTrackerPlacesExitedUnderParent(t *testing.T) {
+	tracker := newChangeTracker()
+
+	// 1
+	// └── 2
+	//     ├── 3
+	//     │   └── 5
+	//     └── 4
+	tracker.update([]*targetTree{{pid: 2, root: startedNode(1, 0, startedNode(2, 1, startedNode(3, 2, startedNode(5, 4)), startedNode(4, 3)))}})
+
+	// 3 and its child 5 exited
+	root := startedNode(1, 0, startedNode(2, 1, startedNode(4, 3)))
+	tracker.update([]*targetTree{{pid: 2, root: root}})
+
+	parent := findTestNode(root, 2)
+	if pids := childPids(parent); !reflect.DeepEqual(pids, []int32{4, 3}) {
+		t.Fatalf("children of PID 2 = %v, want [4 3]", pids)
+	}
+	ghost := parent.Children[1]
+	if !ghost.exited || ghost.Parent != parent || ghost.Depth != parent.Depth+1 {
+		t.Errorf("PID 3 should be an exited child of PID 2 at depth %d, got exited=%v depth=%d", parent.Depth+1, ghost.exited, ghost.Depth)
+	}
+	if pids := childPids(ghost); !reflect.DeepEqual(pids, []int32{5}) || !ghost.Children[0].exited {
+		t.Errorf("children of exited PID 3 = %v, want exited PID 5", pids)
+	}
+	if len(tracker.exited) != 0 {
+		t.Errorf("exited processes placed in the tree should not be listed separately, got %v", tracker.exited)
+	}
+
+	// Exited processes are only shown for one refresh
+	root = startedNode(1, 0, startedNode(2, 1, startedNode(4, 3)))
+	tracker.update([]*targetTree{{pid: 2, root: root}})
+	if pids := childPids(findTestNode(root, 2)); !reflect.DeepEqual(pids, []int32{4}) {
+		t.Errorf("children of PID 2 on the next refresh = %v, want [4]", pids)
+	}
+}
+
+func TestChangeTrackerMarksSwings(t *testing.T) {
+	tracker := newChangeTracker()
+	tracker.update([]*targetTree{{pid: 1, root: testNode(processInfo{Pid: 1, Start: testStart, CPU: 5, RSS: 100 << 20})}})
+
+	root := testNode(processInfo{Pid: 1, Start: testStart, CPU: 30, RSS: 100 << 20})
+	tracker.update([]*targetTree{{pid: 1, root: root}})
+	if !root.swung || root.appeared {
+		t.Errorf("a CPU jump from 5%% to 30%% should be marked as a swing")
+	}
+
+	// The swing is measured against the previous refresh, not the first one
+	root = testNode(processInfo{Pid: 1, Start: testStart, CPU: 31, RSS: 100 << 20})
+	tracker.update([]*targetTree{{pid: 1, root: root}})
+	if root.swung {
+		t.Errorf("a CPU change from 30%% to 31%% should not be marked as a swing")
+	}
+}
+
+func TestSwung(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur processInfo
+		want      bool
+	}{
+		{"unchanged", processInfo{CPU: 10, RSS: 100 << 20}, processInfo{CPU: 10, RSS: 100 << 20}, false},
+		{"cpu rise at threshold", processInfo{CPU: 10}, processInfo{CPU: 30}, true},
+		{"cpu drop at threshold", processInfo{CPU: 50}, processInfo{CPU: 30}, true},
+		{"cpu below threshold", processInfo{CPU: 10}, processInfo{CPU: 29.9}, false},
+		{"rss grows by a quarter", processInfo{RSS: 100 << 20}, processInfo{RSS: 125 << 20}, true},
+		{"rss shrinks by a quarter", processInfo{RSS: 100 << 20}, processInfo{RSS: 75 << 20}, true},
+		{"rss grows by less than a quarter", processInfo{RSS: 100 << 20}, processInfo{RSS: 124 << 20}, false},
+		{"small rss grows tenfold", processInfo{RSS: 512 << 10}, processInfo{RSS: 5 << 20}, false},
+		{"rss grows by the minimum", processInfo{RSS: 1 << 20}, processInfo{RSS: 11 << 20}, true},
+		{"large rss grows by less than a quarter", processInfo{RSS: 1 << 30}, processInfo{RSS: 1<<30 + 100<<20}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := swung(tt.prev, tt.cur); got != tt.want {
+				t.Errorf("swung(%+v, %+v) = %v, want %v", tt.prev, tt.cur, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeTrackerListsExitedWithoutParent(t *testing.T) {
+	tracker := newChangeTracker()
+
+	// 1       10
+	// └── 2   └── 11
+	tracker.update([]*targetTree{
+		{pid: 1, root: startedNode(1, 0, startedNode(2, 1))},
+		{pid: 10, root: startedNode(10, 2, startedNode(11, 3))},
+	})
+
+	// The second target and its child exited, so neither has a parent to be shown under
+	root := startedNode(1, 0, startedNode(2, 1))
+	tracker.update([]*targetTree{{pid: 1, root: root}})
+
+	var pids []int32
+	for _, info := range tracker.exited {
+		pids = append(pids, info.Pid)
+	}
+	if !reflect.DeepEqual(pids, []int32{10, 11}) {
+		t.Errorf("exited = %v, want [10 11]", pids)
+	}
+	if len(appendNodes(nil, root)) != 2 {
+		t.Errorf("exited processes without a shown parent should not be added to the tree")
+	}
+
+	// The list only covers the latest refresh
+	tracker.update([]*targetTree{{pid: 1, root: startedNode(1, 0, startedNode(2, 1))}})
+	if len(tracker.exited) != 0 {
+		t.Errorf("exited = %v on the next refresh, want none", tracker.exited)
+	}
+}
+
+func TestChangeTrackerReset(t *testing.T) {
+	tracker := newChangeTracker()
+	tracker.update([]*targetTree{{pid: 1, root: startedNode(1, 0)}})
+	tracker.reset()
+
+	// After the targets changed, the next refresh is treated like the first one
+	root := startedNode(20, 5)
+	tracker.update([]*targetTree{{pid: 20, root: root}})
+	if root.appeared {
+		t.Errorf("PID 20 marked as appeared on the first refresh after a reset")
+	}
+	if len(tracker.exited) != 0 {
+		t.Errorf("exited = %v after a reset, want none", tracker.exited)
+	}
+}
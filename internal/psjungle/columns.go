@@ -0,0 +1,238 @@
+package psjungle
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// column renders one field of a process line
+type column func(info processInfo) string
+
+// columns maps the names accepted by --columns to their renderers
+var columns = map[string]column{
+	"pid": func(info processInfo) string {
+		// Processes in another PID namespace also show their PID inside it, e.g. 2360[1]
+		if nspid := innermostPid(info.NSpid); nspid != 0 {
+			return fmt.Sprintf("%d[%d]", info.Pid, nspid)
+		}
+		return fmt.Sprintf("%d", info.Pid)
+	},
+	"nspid": func(info processInfo) string {
+		if nspid := innermostPid(info.NSpid); nspid != 0 {
+			return fmt.Sprintf("%d", nspid)
+		}
+		return "-"
+	},
+	"pidns": func(info processInfo) string {
+		if info.PidNamespace == 0 {
+			return "?"
+		}
+		return fmt.Sprintf("%d", info.PidNamespace)
+	},
+	"ppid": func(info processInfo) string {
+		return fmt.Sprintf("%d", info.Ppid)
+	},
+	"user": func(info processInfo) string {
+		if info.User == "" {
+			return "?"
+		}
+		return info.User
+	},
+	"cpu": func(info processInfo) string {
+		return fmt.Sprintf("%.1f", info.CPU)
+	},
+	"rss": func(info processInfo) string {
+		return formatMemory(info.RSS / 1024)
+	},
+	"vsz": func(info processInfo) string {
+		return formatMemory(info.VSZ / 1024)
+	},
+	"pss": func(info processInfo) string {
+		return formatProportional(info.PSS)
+	},
+	"uss": func(info processInfo) string {
+		return formatProportional(info.USS)
+	},
+	"cgroup": func(info processInfo) string {
+		if info.Cgroup == "" {
+			return "?"
+		}
+		return info.Cgroup
+	},
+	"container": func(info processInfo) string {
+		if info.Container == nil {
+			return "-"
+		}
+		return info.Container.String()
+	},
+	"unit": func(info processInfo) string {
+		if info.Unit == "" {
+			return "-"
+		}
+		return info.Unit
+	},
+	"slice": func(info processInfo) string {
+		if info.Slice == "" {
+			return "-"
+		}
+		return info.Slice
+	},
+	"threads": func(info processInfo) string {
+		return fmt.Sprintf("%d", info.Threads)
+	},
+	"start": func(info processInfo) string {
+		return formatStartTime(info.Start)
+	},
+	"name": func(info processInfo) string {
+		return info.Name
+	},
+	"cmd": func(info processInfo) string {
+		return info.Cmdline
+	},
+}
+
+// columnAliases maps alternative column names to the names in columns
+var columnAliases = map[string]string{
+	"mem":     "rss",
+	"memory":  "rss",
+	"cmdline": "cmd",
+	"command": "cmd",
+	"nlwp":    "threads",
+	"started": "start",
+}
+
+// defaultColumns is the layout used when neither --columns nor --format is given
+var defaultColumns = []string{"pid", "user", "cpu", "rss", "cmd"}
+
+// showsColumn reports whether the tree lines show any of the named columns: the
+// --columns selection, or the default layout when no --format template is given
+func showsColumn(opts *options, names ...string) bool {
+	shown := opts.columns
+	if len(shown) == 0 && opts.format == nil {
+		shown = defaultColumns
+	}
+	for _, column := range shown {
+		for _, name := range names {
+			if column == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseColumns parses a comma-separated list of column names
+func parseColumns(spec string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if alias, ok := columnAliases[name]; ok {
+			name = alias
+		}
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("invalid column '%s' (available: %s)", name, strings.Join(columnNames(), ", "))
+		}
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no columns specified")
+	}
+
+	return names, nil
+}
+
+// columnNames returns the sorted list of available column names
+func columnNames() []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFormat parses a --format template and checks it against an empty process
+func parseFormat(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"mem": func(bytes uint64) string {
+			return formatMemory(bytes / 1024)
+		},
+		"start": formatStartTime,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format: %v", err)
+	}
+
+	// Catch references to unknown fields before any output is written
+	if err := tmpl.Execute(&bytes.Buffer{}, processInfo{}); err != nil {
+		return nil, fmt.Errorf("invalid format: %v", err)
+	}
+
+	return tmpl, nil
+}
+
+// formatProportional formats PSS or USS, which are unknown for processes whose
+// smaps can't be read (other users' processes, or platforms other than Linux)
+func formatProportional(bytes uint64) string {
+	if bytes == 0 {
+		return "?"
+	}
+	return formatMemory(bytes / 1024)
+}
+
+// formatStartTime formats a process start time like ps: the time of day for
+// processes started today and the date for older ones
+func formatStartTime(start time.Time) string {
+	if start.IsZero() {
+		return "?"
+	}
+	now := time.Now()
+	if start.Year() == now.Year() && start.YearDay() == now.YearDay() {
+		return start.Format("15:04")
+	}
+	if start.Year() == now.Year() {
+		return start.Format("Jan02")
+	}
+	return start.Format("2006")
+}
+
+// formatNodeLine formats the process fields of a tree line using the
+// --format template when given, or the selected columns otherwise.
+// Subtree totals from --aggregate come first, so they stay visible when long
+// command lines are truncated.
+func formatNodeLine(info processInfo, opts *options) string {
+	if info.Total != nil {
+		return info.Total.String() + " " + formatFields(info, opts)
+	}
+	return formatFields(info, opts)
+}
+
+// formatFields formats the process fields of a tree line
+func formatFields(info processInfo, opts *options) string {
+	if opts.format != nil {
+		var b bytes.Buffer
+		if err := opts.format.Execute(&b, info); err != nil {
+			return fmt.Sprintf("%d <format error: %v>", info.Pid, err)
+		}
+		return b.String()
+	}
+
+	names := opts.columns
+	if len(names) == 0 {
+		names = defaultColumns
+	}
+
+	fields := make([]string, len(names))
+	for i, name := range names {
+		fields[i] = columns[name](info)
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,265 @@
+package psjungle
+
+import (
+	"os"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// cpuSnapshot is the accumulated CPU time of a process at a point in time, and the
+// CPU% measured when it was taken
+type cpuSnapshot struct {
+	seconds   float64
+	createdAt int64
+	takenAt   time.Time
+	percent   float64
+	measured  bool
+}
+
+// cpuSampler reports instantaneous CPU% the way top does: from the CPU time a
+// process used between two snapshots. Snapshots are kept between calls, so in
+// watch mode the delta between consecutive refreshes is used. One sampler is
+// shared by --where, --top and the trees: a process measured less than the
+// interval ago keeps that CPU% instead of being measured over a shorter span.
+type cpuSampler struct {
+	interval  time.Duration
+	snapshots map[int32]cpuSnapshot
+
+	// watch is set in watch mode, where only the first refresh waits for the interval.
+	// Processes that appear later get a first snapshot and their CPU% from the next
+	// refresh on, so a burst of new processes doesn't stall the screen.
+	watch   bool
+	sampled bool
+
+	// threadSnapshots are keyed by TID; the main thread has the same ID as its process
+	threadSnapshots map[int32]cpuSnapshot
+}
+
+// newCPUSampler creates a sampler that waits for interval when a process has
+// no earlier snapshot. A zero interval disables sampling.
+func newCPUSampler(interval time.Duration) *cpuSampler {
+	return &cpuSampler{
+		interval:        interval,
+		snapshots:       make(map[int32]cpuSnapshot),
+		threadSnapshots: make(map[int32]cpuSnapshot),
+	}
+}
+
+// enabled reports whether CPU% should be sampled instead of using lifetime averages
+func (s *cpuSampler) enabled() bool {
+	return s != nil && s.interval > 0
+}
+
+// takeSnapshot reads the accumulated CPU time of a process
+func takeSnapshot(proc *process.Process) (cpuSnapshot, bool) {
+	times, err := proc.Times()
+	if err != nil {
+		return cpuSnapshot{}, false
+	}
+	createdAt, _ := proc.CreateTime()
+	return cpuSnapshot{
+		seconds:   times.User + times.System,
+		createdAt: createdAt,
+		takenAt:   time.Now(),
+	}, true
+}
+
+// cpuTask is a process or thread whose CPU time is sampled
+type cpuTask struct {
+	id        int32
+	createdAt int64
+	snapshot  func() (cpuSnapshot, bool)
+}
+
+// tasksOfProcesses returns the sampling tasks of processes, keyed by PID
+func tasksOfProcesses(procs []*process.Process) []cpuTask {
+	tasks := make([]cpuTask, len(procs))
+	for i, proc := range procs {
+		proc := proc
+		createdAt, _ := proc.CreateTime()
+		tasks[i] = cpuTask{
+			id:        proc.Pid,
+			createdAt: createdAt,
+			snapshot:  func() (cpuSnapshot, bool) { return takeSnapshot(proc) },
+		}
+	}
+	return tasks
+}
+
+// tasksOfThreads returns the sampling tasks of threads, keyed by TID
+func tasksOfThreads(threads []*threadInfo) []cpuTask {
+	tasks := make([]cpuTask, len(threads))
+	for i, thread := range threads {
+		pid, tid := thread.pid, thread.Tid
+		tasks[i] = cpuTask{
+			id:        tid,
+			createdAt: thread.start,
+			snapshot:  func() (cpuSnapshot, bool) { return threadSnapshot(pid, tid) },
+		}
+	}
+	return tasks
+}
+
+// prime takes a first snapshot of the tasks without a usable previous one and
+// returns their IDs
+func prime(snapshots map[int32]cpuSnapshot, tasks []cpuTask) map[int32]bool {
+	primed := make(map[int32]bool)
+	for _, task := range tasks {
+		if prev, ok := snapshots[task.id]; ok && prev.createdAt == task.createdAt {
+			continue
+		}
+		if snap, ok := task.snapshot(); ok {
+			snapshots[task.id] = snap
+			primed[task.id] = true
+		}
+	}
+	return primed
+}
+
+// withoutTasks returns the tasks whose IDs are not in ids
+func withoutTasks(tasks []cpuTask, ids map[int32]bool) []cpuTask {
+	var kept []cpuTask
+	for _, task := range tasks {
+		if !ids[task.id] {
+			kept = append(kept, task)
+		}
+	}
+	return kept
+}
+
+// measure returns the CPU% of each task since its snapshot in snapshots, and the new snapshots.
+// Tasks measured less than interval ago keep their snapshot and CPU%.
+func measure(snapshots map[int32]cpuSnapshot, tasks []cpuTask, interval time.Duration) (map[int32]float64, map[int32]cpuSnapshot) {
+	result := make(map[int32]float64, len(tasks))
+	current := make(map[int32]cpuSnapshot, len(tasks))
+	for _, task := range tasks {
+		prev, seen := snapshots[task.id]
+		if seen && prev.createdAt == task.createdAt && prev.measured && time.Since(prev.takenAt) < interval {
+			result[task.id] = prev.percent
+			current[task.id] = prev
+			continue
+		}
+
+		snap, ok := task.snapshot()
+		if !ok {
+			continue
+		}
+		current[task.id] = snap
+
+		if !seen || prev.createdAt != snap.createdAt {
+			continue
+		}
+		elapsed := snap.takenAt.Sub(prev.takenAt).Seconds()
+		if elapsed <= 0 {
+			continue
+		}
+		snap.percent = 100 * (snap.seconds - prev.seconds) / elapsed
+		snap.measured = true
+		current[task.id] = snap
+		result[task.id] = snap.percent
+	}
+	return result, current
+}
+
+// percentages returns the CPU% of each process since its previous snapshot.
+// Processes without a usable previous snapshot are sampled over the sampler interval first.
+func (s *cpuSampler) percentages(procs []*process.Process) map[int32]float64 {
+	// psjungle is never a candidate of --where or --top, but shows up in the trees of the
+	// shell that started it, so it is sampled along with them for the trees to reuse
+	if self, err := process.NewProcess(int32(os.Getpid())); err == nil {
+		procs = append(procs[:len(procs):len(procs)], self)
+	}
+	cpu, _ := s.sample(procs, nil)
+	return cpu
+}
+
+// sample returns the CPU% of processes and threads since their previous snapshots,
+// keyed by PID and TID. Both are sampled over the same interval when needed; in watch
+// mode, the ones first seen after the first refresh are left out instead.
+func (s *cpuSampler) sample(procs []*process.Process, threads []*threadInfo) (map[int32]float64, map[int32]float64) {
+	procTasks, threadTasks := tasksOfProcesses(procs), tasksOfThreads(threads)
+
+	primedProcs := prime(s.snapshots, procTasks)
+	primedThreads := prime(s.threadSnapshots, threadTasks)
+	deferNew := s.watch && s.sampled
+	switch {
+	case len(primedProcs) == 0 && len(primedThreads) == 0:
+	case deferNew:
+		// Their first snapshots are kept below and measured on the next refresh
+		procTasks = withoutTasks(procTasks, primedProcs)
+		threadTasks = withoutTasks(threadTasks, primedThreads)
+	default:
+		time.Sleep(s.interval)
+	}
+	s.sampled = true
+
+	// Only keep the threads seen in this round, so exited IDs don't accumulate. Processes
+	// left out by this caller, e.g. the trees after --where sampled every process, are
+	// kept while they run so the next refresh doesn't wait for the interval again.
+	procCPU, current := measure(s.snapshots, procTasks, s.interval)
+	if len(current) < len(s.snapshots) {
+		running, _ := process.Pids()
+		for _, pid := range running {
+			if snap, ok := s.snapshots[pid]; ok {
+				if _, seen := current[pid]; !seen {
+					current[pid] = snap
+				}
+			}
+		}
+	}
+	s.snapshots = current
+	threadCPU, currentThreads := measure(s.threadSnapshots, threadTasks, s.interval)
+	if deferNew {
+		for tid := range primedThreads {
+			currentThreads[tid] = s.threadSnapshots[tid]
+		}
+	}
+	s.threadSnapshots = currentThreads
+	return procCPU, threadCPU
+}
+
+// apply replaces the lifetime CPU averages of every node in the trees, and of
+// their threads with --threads, with sampled values. Processes and threads that
+// could not be sampled yet keep their lifetime averages.
+func (s *cpuSampler) apply(trees []*targetTree) {
+	if !s.enabled() {
+		return
+	}
+
+	var nodes []*ProcessNode
+	for _, t := range trees {
+		nodes = appendNodes(nodes, t.root)
+	}
+
+	procs := make([]*process.Process, len(nodes))
+	var threads []*threadInfo
+	for i, node := range nodes {
+		procs[i] = node.Process
+		info := getProcessInfo(node)
+		for j := range info.Tasks {
+			threads = append(threads, &node.info.Tasks[j])
+		}
+	}
+
+	cpu, threadCPU := s.sample(procs, threads)
+	for _, node := range nodes {
+		if percent, ok := cpu[node.Process.Pid]; ok {
+			node.info.CPU = percent
+		}
+	}
+	for _, thread := range threads {
+		if percent, ok := threadCPU[thread.Tid]; ok {
+			thread.CPU = percent
+		}
+	}
+}
+
+// appendNodes appends node and all of its descendants to nodes
+func appendNodes(nodes []*ProcessNode, node *ProcessNode) []*ProcessNode {
+	nodes = append(nodes, node)
+	for _, child := range node.Children {
+		nodes = appendNodes(nodes, child)
+	}
+	return nodes
+}
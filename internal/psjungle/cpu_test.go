@@ -0,0 +1,73 @@
+package psjungle
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// fixedTask returns a sampling task whose snapshot reports seconds of CPU time
+func fixedTask(id int32, seconds *float64) cpuTask {
+	return cpuTask{
+		id:        id,
+		createdAt: 1,
+		snapshot: func() (cpuSnapshot, bool) {
+			return cpuSnapshot{seconds: *seconds, createdAt: 1, takenAt: time.Now()}, true
+		},
+	}
+}
+
+func TestMeasureReusesRecentSample(t *testing.T) {
+	seconds := 0.0
+	task := fixedTask(1, &seconds)
+	snapshots := map[int32]cpuSnapshot{
+		1: {seconds: 0, createdAt: 1, takenAt: time.Now().Add(-time.Second)},
+	}
+
+	// Half a second of CPU time over a second
+	seconds = 0.5
+	cpu, snapshots := measure(snapshots, []cpuTask{task}, 500*time.Millisecond)
+	if cpu[1] < 45 || cpu[1] > 55 {
+		t.Fatalf("expected about 50%% CPU, got %.1f", cpu[1])
+	}
+
+	// Measured again right away, e.g. by the trees after --where: the sample is reused
+	seconds = 1.5
+	again, snapshots := measure(snapshots, []cpuTask{task}, 500*time.Millisecond)
+	if again[1] != cpu[1] {
+		t.Errorf("expected the recent %.1f%% to be reused, got %.1f", cpu[1], again[1])
+	}
+
+	// Once the interval has passed, a new sample covers the CPU time since the first one
+	snap := snapshots[1]
+	snap.takenAt = snap.takenAt.Add(-time.Second)
+	snapshots[1] = snap
+	later, _ := measure(snapshots, []cpuTask{task}, 500*time.Millisecond)
+	if later[1] < 95 || later[1] > 105 {
+		t.Errorf("expected about 100%% CPU after the interval, got %.1f", later[1])
+	}
+}
+
+func TestSampleSkipsWaitForNewProcessesInWatchMode(t *testing.T) {
+	self, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		t.Fatalf("failed to read own process: %v", err)
+	}
+
+	// A later refresh in watch mode: a process seen for the first time is only snapshotted
+	s := newCPUSampler(time.Hour)
+	s.watch, s.sampled = true, true
+	started := time.Now()
+	cpu, _ := s.sample([]*process.Process{self}, nil)
+	if elapsed := time.Since(started); elapsed > time.Minute {
+		t.Fatalf("expected no wait for a new process, took %s", elapsed)
+	}
+	if _, ok := cpu[self.Pid]; ok {
+		t.Errorf("expected no CPU%% before the next refresh, got %.1f", cpu[self.Pid])
+	}
+	if _, ok := s.snapshots[self.Pid]; !ok {
+		t.Errorf("expected a first snapshot of PID %d", self.Pid)
+	}
+}
@@ -0,0 +1,91 @@
+package psjungle
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// fileInfo describes one open file descriptor of a process, as shown by --files
+type fileInfo struct {
+	Fd   uint64 `json:"fd"`
+	Path string `json:"path"`
+	Mode string `json:"mode,omitempty"`
+	Type string `json:"type"`
+}
+
+// String formats the descriptor like lsof: fd number with access mode, type and path
+func (f fileInfo) String() string {
+	return fmt.Sprintf("%-5s %-8s %s", fmt.Sprintf("%d%s", f.Fd, f.Mode), f.Type, f.Path)
+}
+
+// classifyFile returns the type of an open file from the path reported by the
+// kernel, and the path without the markers used for special files
+func classifyFile(path string) (string, string) {
+	switch {
+	case strings.HasPrefix(path, "pipe:"):
+		return "pipe", path
+	case strings.HasPrefix(path, "socket:"):
+		return "socket", path
+	case strings.HasPrefix(path, "anon_inode:"):
+		return "anon", path
+	case strings.HasSuffix(path, " (deleted)"):
+		return "deleted", strings.TrimSuffix(path, " (deleted)")
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return "unknown", path
+	}
+	switch mode := st.Mode(); {
+	case mode.IsRegular():
+		return "regular", path
+	case mode.IsDir():
+		return "dir", path
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo", path
+	case mode&os.ModeSocket != 0:
+		return "socket", path
+	case mode&os.ModeCharDevice != 0:
+		return "char", path
+	case mode&os.ModeDevice != 0:
+		return "block", path
+	}
+	return "unknown", path
+}
+
+// openFiles lists the open file descriptors of a process, ordered by fd number
+func openFiles(node *ProcessNode) []fileInfo {
+	stats, err := node.Process.OpenFiles()
+	if err != nil {
+		// Usually a process owned by another user
+		return nil
+	}
+
+	files := make([]fileInfo, 0, len(stats))
+	for _, stat := range stats {
+		fileType, path := classifyFile(stat.Path)
+		files = append(files, fileInfo{
+			Fd:   stat.Fd,
+			Path: path,
+			Mode: fdMode(node.Process.Pid, stat.Fd),
+			Type: fileType,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Fd < files[j].Fd
+	})
+	return files
+}
+
+// attachFiles looks up the open files of every node in the trees
+func attachFiles(trees []*targetTree) {
+	for _, t := range trees {
+		for _, node := range appendNodes(nil, t.root) {
+			getProcessInfo(node)
+			node.info.Files = openFiles(node)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package psjungle
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fdMode returns the access mode of a file descriptor (r, w or u for read-write,
+// like lsof) from the flags in /proc/<pid>/fdinfo/<fd>
+func fdMode(pid int32, fd uint64) string {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/fdinfo/%d", pid, fd))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return ""
+		}
+		switch flags & uint64(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) {
+		case uint64(os.O_WRONLY):
+			return "w"
+		case uint64(os.O_RDWR):
+			return "u"
+		default:
+			return "r"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package psjungle
+
+// fdMode is not available on this platform
+func fdMode(pid int32, fd uint64) string {
+	return ""
+}
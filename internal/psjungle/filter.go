@@ -0,0 +1,84 @@
+package psjungle
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Predicate selects processes. Lookups such as ByRegex and ByPort find candidate
+// PIDs, and FilterPids narrows them down with predicates such as --where and --user.
+type Predicate interface {
+	Match(proc *process.Process) bool
+}
+
+// preparer is implemented by predicates that need to look at all candidates
+// before matching them one by one, e.g. to sample CPU% in a single interval
+type preparer interface {
+	prepare(procs []*process.Process)
+}
+
+// FilterPids returns the PIDs whose processes match every predicate, in their original order
+func FilterPids(pids []int, preds ...Predicate) []int {
+	if len(preds) == 0 {
+		return pids
+	}
+
+	var procs []*process.Process
+	for _, pid := range pids {
+		proc, err := process.NewProcess(int32(pid))
+		if err != nil {
+			continue
+		}
+		procs = append(procs, proc)
+	}
+
+	for _, pred := range preds {
+		if p, ok := pred.(preparer); ok {
+			p.prepare(procs)
+		}
+	}
+
+	var matches []int
+	for _, proc := range procs {
+		matched := true
+		for _, pred := range preds {
+			if !pred.Match(proc) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, int(proc.Pid))
+		}
+	}
+	return matches
+}
+
+// AllPids returns the PIDs of every process except psjungle itself, for
+// predicates used without any other input
+func AllPids() ([]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	currentPid := int32(os.Getpid())
+	for _, proc := range procs {
+		// Skip the current process (psjungle itself)
+		if proc.Pid == currentPid {
+			continue
+		}
+		pids = append(pids, int(proc.Pid))
+	}
+
+	sort.Ints(pids)
+	return pids, nil
+}
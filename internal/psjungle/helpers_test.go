@@ -0,0 +1,37 @@
+package psjungle
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// testNode builds a tree node with its displayable fields already filled in, so
+// nothing is read from the process table
+func testNode(info processInfo, children ...*ProcessNode) *ProcessNode {
+	if info.Name == "" {
+		info.Name = fmt.Sprintf("proc%d", info.Pid)
+	}
+	if info.Cmdline == "" {
+		info.Cmdline = info.Name
+	}
+	node := &ProcessNode{
+		Process:  &process.Process{Pid: info.Pid},
+		Children: children,
+		info:     &info,
+	}
+	for _, child := range children {
+		child.Parent = node
+	}
+	adjustDepths(node, 0)
+	return node
+}
+
+// childPids returns the PIDs of the children of node, in order
+func childPids(node *ProcessNode) []int32 {
+	pids := make([]int32, len(node.Children))
+	for i, child := range node.Children {
+		pids[i] = child.Process.Pid
+	}
+	return pids
+}
@@ -0,0 +1,108 @@
+package psjungle
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostFilter matches connection addresses against a --host value: an IP
+// address, a CIDR range, an interface name, localhost, or a wildcard address
+type hostFilter struct {
+	ips  []net.IP
+	nets []*net.IPNet
+
+	// wildcard is set for 0.0.0.0, :: and *, which only match sockets bound to all interfaces
+	wildcard bool
+}
+
+// parseHostFilter parses a --host value. Interface names are resolved to their local addresses.
+func parseHostFilter(host string) (*hostFilter, error) {
+	host = strings.TrimSpace(host)
+	// Accept bracketed IPv6 literals such as [::1]
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+
+	if host == "*" {
+		return &hostFilter{wildcard: true}, nil
+	}
+	if strings.EqualFold(host, "localhost") {
+		return &hostFilter{ips: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}}, nil
+	}
+
+	if ip := parseIP(host); ip != nil {
+		if ip.IsUnspecified() {
+			return &hostFilter{wildcard: true}, nil
+		}
+		return &hostFilter{ips: []net.IP{ip}}, nil
+	}
+
+	if strings.Contains(host, "/") {
+		_, ipNet, err := net.ParseCIDR(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range '%s'", host)
+		}
+		return &hostFilter{nets: []*net.IPNet{ipNet}}, nil
+	}
+
+	iface, err := net.InterfaceByName(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host '%s' (expected an IP address, CIDR range or interface name)", host)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the addresses of interface '%s': %v", host, err)
+	}
+	f := &hostFilter{}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			f.ips = append(f.ips, ipNet.IP)
+		}
+	}
+	return f, nil
+}
+
+// parseIP parses an IP address, ignoring an IPv6 zone such as %eth0
+func parseIP(s string) net.IP {
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	return net.ParseIP(s)
+}
+
+// matches reports whether a connection address matches the filter. Sockets
+// bound to all interfaces (0.0.0.0, :: or *) match any filter, since they
+// accept connections on every address, and a wildcard filter only matches them.
+func (f *hostFilter) matches(addr string) bool {
+	var ip net.IP
+	switch {
+	case addr == "*" || addr == "":
+		return true
+	case strings.EqualFold(addr, "localhost"):
+		ip = net.IPv4(127, 0, 0, 1)
+	default:
+		ip = parseIP(addr)
+	}
+	if ip == nil {
+		return false
+	}
+	if ip.IsUnspecified() {
+		return true
+	}
+	if f.wildcard {
+		return false
+	}
+
+	for _, want := range f.ips {
+		if want.Equal(ip) {
+			return true
+		}
+	}
+	for _, ipNet := range f.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package psjungle
+
+import "testing"
+
+func TestHostFilterMatches(t *testing.T) {
+	tests := []struct {
+		host string
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", "127.0.0.1", true},
+		{"127.0.0.1", "127.0.0.2", false},
+		{"localhost", "127.0.0.1", true},
+		{"localhost", "::1", true},
+		{"::1", "::1", true},
+		{"[::1]", "::1", true},
+		{"::1", "127.0.0.1", false},
+		{"fe80::1", "fe80::1%eth0", true},
+		{"10.0.0.0/8", "10.1.2.3", true},
+		{"10.0.0.0/8", "192.168.1.1", false},
+		{"fd00::/8", "fd12::5", true},
+		{"fd00::/8", "fe80::1", false},
+
+		// Sockets bound to all interfaces match any host, wildcard hosts only match them
+		{"127.0.0.1", "0.0.0.0", true},
+		{"fd00::/8", "::", true},
+		{"::1", "*", true},
+		{"0.0.0.0", "0.0.0.0", true},
+		{"0.0.0.0", "::", true},
+		{"[::]", "0.0.0.0", true},
+		{"*", "::", true},
+		{"0.0.0.0", "127.0.0.1", false},
+		{"::", "::1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+" "+tt.addr, func(t *testing.T) {
+			f, err := parseHostFilter(tt.host)
+			if err != nil {
+				t.Fatalf("parseHostFilter(%q) failed: %v", tt.host, err)
+			}
+			if got := f.matches(tt.addr); got != tt.want {
+				t.Errorf("--host %s matches %s = %v, want %v", tt.host, tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHostFilterInvalid(t *testing.T) {
+	for _, host := range []string{"10.0.0.0/99", "fd00::/129", "psjungle-no-such-interface"} {
+		if _, err := parseHostFilter(host); err == nil {
+			t.Errorf("parseHostFilter(%q) should fail", host)
+		}
+	}
+}
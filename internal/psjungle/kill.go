@@ -0,0 +1,274 @@
+package psjungle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Orders accepted by --kill-order
+const (
+	killOrderRoot   = "root"
+	killOrderLeaves = "leaves"
+)
+
+// graceInterval is how often --kill-grace checks whether the signaled processes exited
+const graceInterval = 100 * time.Millisecond
+
+// killTarget is a process to signal with --kill-tree or --kill-grace. The start time read
+// with the tree tells it apart from a process that got the same PID after it exited.
+type killTarget struct {
+	pid   int
+	start int64 // milliseconds since the epoch, 0 when unknown
+}
+
+// errAlreadyExited is returned when signaling a target that is gone
+var errAlreadyExited = errors.New("it already exited")
+
+// signal sends sig to the target, unless its PID now belongs to another process
+func (t killTarget) signal(sig syscall.Signal) error {
+	proc, err := process.NewProcess(int32(t.pid))
+	if err != nil {
+		return errAlreadyExited
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil || t.start == 0 {
+		return errors.New("could not verify its start time")
+	}
+	if createTime != t.start {
+		return errors.New("its PID was reused by another process")
+	}
+	return proc.SendSignal(sig)
+}
+
+// alive reports whether the target is still running. A process whose parent has not
+// reaped it yet is done as far as signals go, so zombies count as exited.
+func (t killTarget) alive() bool {
+	proc, err := process.NewProcess(int32(t.pid))
+	if err != nil {
+		return false
+	}
+	// Without a start time a live process on the PID has to be taken for the target
+	if createTime, err := proc.CreateTime(); t.start != 0 && (err != nil || createTime != t.start) {
+		return false
+	}
+	status, err := proc.Status()
+	return err != nil || len(status) == 0 || status[0] != process.Zombie
+}
+
+// signalMatches sends the -k signal to the processed PIDs, or with --kill-tree to every
+// process in their subtrees
+func signalMatches(trees []*targetTree, processedPids []int, signal syscall.Signal, opts *options, w io.Writer) {
+	if opts.killTree {
+		signalTargets(subtreeTargets(trees, opts.killOrder), signal, w)
+		return
+	}
+	signalPids(processedPids, signal, w)
+}
+
+// killTargets returns the processes signaled for the trees: their targets, or with
+// --kill-tree every process in their subtrees
+func killTargets(trees []*targetTree, opts *options) []killTarget {
+	if opts.killTree {
+		return subtreeTargets(trees, opts.killOrder)
+	}
+
+	var targets []killTarget
+	for _, t := range trees {
+		if target := findTargetNode(t.root, t.pid); target != nil && !target.exited {
+			targets = append(targets, killTargetOf(target))
+		}
+	}
+	return targets
+}
+
+// killTargetOf returns the kill target for a tree node
+func killTargetOf(node *ProcessNode) killTarget {
+	target := killTarget{pid: int(node.Process.Pid)}
+	if info := getProcessInfo(node); !info.Start.IsZero() {
+		target.start = info.Start.UnixMilli()
+	}
+	return target
+}
+
+// subtreeTargets returns the target of each tree and all of its descendants, root first
+// or leaves first. Processes that already exited and psjungle itself are left out.
+func subtreeTargets(trees []*targetTree, order string) []killTarget {
+	self := os.Getpid()
+	seen := make(map[int]bool)
+	var targets []killTarget
+	for _, t := range trees {
+		target := findTargetNode(t.root, t.pid)
+		if target == nil {
+			continue
+		}
+
+		nodes := make(map[int]*ProcessNode)
+		for _, node := range appendNodes(nil, target) {
+			nodes[int(node.Process.Pid)] = node
+		}
+		for _, pid := range collectProcessTreePids(target) {
+			node := nodes[pid]
+			if seen[pid] || pid == self || node.exited {
+				continue
+			}
+			seen[pid] = true
+			targets = append(targets, killTargetOf(node))
+		}
+	}
+
+	// Every descendant comes after its ancestors, so the reverse has the leaves first
+	if order == killOrderLeaves {
+		for i, j := 0, len(targets)-1; i < j; i, j = i+1, j-1 {
+			targets[i], targets[j] = targets[j], targets[i]
+		}
+	}
+	return targets
+}
+
+// signalTargets sends the signal to the targets in order and reports the outcome to w.
+// Targets whose PID now belongs to a process with another start time are skipped.
+func signalTargets(targets []killTarget, signal syscall.Signal, w io.Writer) {
+	for _, target := range targets {
+		if err := target.signal(signal); err != nil {
+			fmt.Fprintf(w, "Warning: Could not send signal to PID %d: %v\n", target.pid, err)
+		} else {
+			fmt.Fprintf(w, "Sent signal %d to PID %d\n", signal, target.pid)
+		}
+	}
+}
+
+// killGracefully sends the signal to the targets and waits up to the --kill-grace period for
+// them to exit, showing the processes still running. Survivors then get SIGKILL, and the
+// outcome for every target is reported. Returns false when some target is still running.
+func killGracefully(targets []killTarget, signal syscall.Signal, opts *options) bool {
+	outcomes := make(map[int]string, len(targets))
+	var pending, unsignaled []killTarget
+	for _, target := range targets {
+		if err := target.signal(signal); err != nil {
+			outcomes[target.pid] = fmt.Sprintf("not signaled: %v", err)
+			unsignaled = append(unsignaled, target)
+			continue
+		}
+		pending = append(pending, target)
+	}
+
+	started := time.Now()
+	deadline := started.Add(opts.killGrace)
+	exited := fmt.Sprintf("exited after signal %d", signal)
+	shownLeft, shownCount := -1, -1
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		// Redraw once a second, or as soon as a process exits
+		left := int(math.Ceil(time.Until(deadline).Seconds()))
+		if left != shownLeft || len(pending) != shownCount {
+			shownLeft, shownCount = left, len(pending)
+			showSurvivors(pending, left, opts)
+		}
+		time.Sleep(graceInterval)
+		pending = reapTargets(pending, outcomes, exited, started)
+	}
+
+	var killed, survivors []killTarget
+	for _, target := range pending {
+		switch err := target.signal(syscall.SIGKILL); err {
+		case nil:
+			killed = append(killed, target)
+		case errAlreadyExited:
+			outcomes[target.pid] = fmt.Sprintf("%s in %s", exited, opts.killGrace)
+		default:
+			outcomes[target.pid] = fmt.Sprintf("still running, signal %d failed: %v", syscall.SIGKILL, err)
+			survivors = append(survivors, target)
+		}
+	}
+
+	// SIGKILL can't be caught, but the kernel still needs a moment to tear the process down
+	killedAt := time.Now()
+	for len(killed) > 0 && time.Since(killedAt) < time.Second {
+		killed = reapTargets(killed, outcomes, fmt.Sprintf("killed with signal %d after %s", syscall.SIGKILL, opts.killGrace), time.Time{})
+		if len(killed) > 0 {
+			time.Sleep(graceInterval)
+		}
+	}
+	for _, target := range killed {
+		outcomes[target.pid] = fmt.Sprintf("still running after signal %d", syscall.SIGKILL)
+		survivors = append(survivors, target)
+	}
+
+	// A target that could not be signaled, e.g. another user's process, is still running
+	// unless it exited on its own in the meantime
+	for _, target := range unsignaled {
+		if target.alive() {
+			outcomes[target.pid] = "still running, " + outcomes[target.pid]
+			survivors = append(survivors, target)
+		}
+	}
+
+	w := opts.messages()
+	for _, target := range targets {
+		fmt.Fprintf(w, "PID %d: %s\n", target.pid, outcomes[target.pid])
+	}
+	return len(survivors) == 0
+}
+
+// reapTargets records the outcome of the targets that exited and returns the ones still
+// running. With a non-zero since, the time it took them to exit is added to the outcome.
+func reapTargets(targets []killTarget, outcomes map[int]string, outcome string, since time.Time) []killTarget {
+	var running []killTarget
+	for _, target := range targets {
+		if target.alive() {
+			running = append(running, target)
+			continue
+		}
+		if since.IsZero() {
+			outcomes[target.pid] = outcome
+		} else {
+			outcomes[target.pid] = fmt.Sprintf("%s in %s", outcome, time.Since(since).Round(graceInterval))
+		}
+	}
+	return running
+}
+
+// showSurvivors shows the countdown to SIGKILL. In a terminal the screen is redrawn with the
+// trees of the processes still running; otherwise a line listing their PIDs is printed.
+func showSurvivors(targets []killTarget, left int, opts *options) {
+	// Parents usually started before their children, so this puts ancestors first and
+	// their trees take in the surviving descendants
+	sorted := append([]killTarget(nil), targets...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+	pids := make([]int, len(sorted))
+	surviving := make(map[int]bool, len(sorted))
+	for i, target := range sorted {
+		pids[i] = target.pid
+		surviving[target.pid] = true
+	}
+	header := fmt.Sprintf("Waiting for %d process(es) to exit, sending signal %d in %ds", len(pids), syscall.SIGKILL, left)
+
+	if opts.jsonOutput() || !isTerminal(os.Stdout) {
+		fmt.Fprintf(opts.messages(), "%s: %s\n", header, strings.Trim(fmt.Sprint(pids), "[]"))
+		return
+	}
+
+	// Sampling CPU% would stall the countdown for the sample interval on every redraw,
+	// so the survivors show their lifetime averages
+	drawOpts := *opts
+	drawOpts.cpu = nil
+	trees, _ := collectTrees(pids, &drawOpts, io.Discard, make(map[int]bool))
+	for _, t := range trees {
+		for _, node := range appendNodes(nil, t.root) {
+			node.IsTarget = surviving[int(node.Process.Pid)]
+		}
+	}
+	fmt.Print("\033[H\033[2J")
+	fmt.Printf("%s\n\n", header)
+	if err := renderTrees(trees, len(trees) > 1, &drawOpts); err != nil {
+		fmt.Println(err)
+	}
+}
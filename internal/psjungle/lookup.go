@@ -2,10 +2,13 @@ package psjungle
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	gonet "github.com/shirou/gopsutil/v3/net"
@@ -106,6 +109,55 @@ func ByRegex(pattern string, strict bool) ([]int, error) {
 // ByPort returns PIDs that have a connection bound to or communicating with the given port.
 // By default, it looks for listening connections on all hosts.
 func ByPort(port uint32, host string) ([]int, error) {
+	return ByPorts([]uint32{port}, host)
+}
+
+// ByPorts returns PIDs that have a connection bound to or communicating with any of the given ports.
+// By default, it looks for listening connections on all hosts.
+func ByPorts(ports []uint32, host string) ([]int, error) {
+	return ByPortQuery(PortQuery{Ports: ports, Host: host})
+}
+
+// PortQuery selects connections for ByPortQuery
+type PortQuery struct {
+	// Ports to match against the local or remote port of each connection
+	Ports []uint32
+	// Host restricts matches to this address: the local address of listening
+	// sockets, or the remote address with Remote. It can be an IPv4 or IPv6
+	// address, a CIDR range, an interface name or localhost. Sockets bound to all
+	// interfaces match any Host, and 0.0.0.0, :: and * only match those sockets.
+	Host string
+	// Proto restricts matches to tcp, udp, tcp6 or udp6 (empty for all)
+	Proto string
+	// State restricts matches to a connection state as reported by gopsutil,
+	// such as LISTEN or ESTABLISHED (empty for all)
+	State string
+	// Remote only matches connections whose remote end is one of Ports,
+	// i.e. the clients of a service instead of the service itself
+	Remote bool
+}
+
+// ConnectionStates lists the connection states accepted by PortQuery.State
+var ConnectionStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+// ByPortQuery returns PIDs that have a connection matching the query
+func ByPortQuery(q PortQuery) ([]int, error) {
+	wanted := make(map[uint32]bool, len(q.Ports))
+	for _, port := range q.Ports {
+		wanted[port] = true
+	}
+
+	var host *hostFilter
+	if q.Host != "" {
+		var err error
+		if host, err = parseHostFilter(q.Host); err != nil {
+			return nil, err
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -120,32 +172,38 @@ func ByPort(port uint32, host string) ([]int, error) {
 			continue
 		}
 
-		// Check port match first
-		portMatch := (conn.Laddr.Port == port || conn.Raddr.Port == port)
-		if !portMatch {
+		if q.Proto != "" && connProto(conn) != q.Proto {
+			continue
+		}
+		if q.State != "" && conn.Status != q.State {
 			continue
 		}
 
-		// Check if we're filtering by host
-		if host != "" {
-			// For host filtering, we only check listening connections
-			if conn.Status != "LISTEN" {
+		if q.Remote {
+			// Clients: the remote end is the port (listening sockets have no remote end)
+			if conn.Raddr.Port == 0 || !wanted[conn.Raddr.Port] {
 				continue
 			}
-			// Check if the local address matches the specified host
-			// Handle special cases: "*" means all hosts, so it should match any host filter
-			hostMatch := (conn.Laddr.IP == host || conn.Laddr.IP == "*" ||
-				(host == "127.0.0.1" && conn.Laddr.IP == "localhost") ||
-				(host == "localhost" && conn.Laddr.IP == "127.0.0.1"))
-			if !hostMatch {
+			if host != nil && !host.matches(conn.Raddr.IP) {
 				continue
 			}
 		} else {
-			// Default behavior: look for listening connections on all hosts
-			// Skip non-listening connections unless they're communicating on the specified port
-			if conn.Status != "LISTEN" && conn.Laddr.Port != port && conn.Raddr.Port != port {
+			// Check port match first
+			if !matchesPort(wanted, conn) {
 				continue
 			}
+
+			// Check if we're filtering by host
+			if host != nil {
+				// For host filtering, we only check listening sockets unless a state is given
+				if q.State == "" && !isBoundService(conn) {
+					continue
+				}
+				// Check if the local address matches the specified host
+				if !host.matches(conn.Laddr.IP) {
+					continue
+				}
+			}
 		}
 
 		if _, ok := seen[conn.Pid]; ok {
@@ -159,6 +217,296 @@ func ByPort(port uint32, host string) ([]int, error) {
 	return matches, nil
 }
 
+// matchesPort reports whether either end of a connection uses one of the wanted ports.
+// An end that is not bound or connected, such as the remote end of a listener, has port 0
+// and never matches, so a range like :0-100 doesn't match every listening socket.
+func matchesPort(wanted map[uint32]bool, conn gonet.ConnectionStat) bool {
+	return (conn.Laddr.Port != 0 && wanted[conn.Laddr.Port]) || (conn.Raddr.Port != 0 && wanted[conn.Raddr.Port])
+}
+
+// connProto returns the protocol name of a connection: tcp, udp, tcp6 or udp6
+func connProto(conn gonet.ConnectionStat) string {
+	proto := "tcp"
+	if conn.Type == syscall.SOCK_DGRAM {
+		proto = "udp"
+	}
+	if conn.Family == syscall.AF_INET6 {
+		proto += "6"
+	}
+	return proto
+}
+
+// isBoundService reports whether a socket serves its port: a listening TCP socket,
+// or a UDP socket that is not connected to a peer, which never has the LISTEN state
+func isBoundService(conn gonet.ConnectionStat) bool {
+	if conn.Type == syscall.SOCK_DGRAM {
+		return (conn.Status == "" || conn.Status == "NONE") && conn.Raddr.Port == 0
+	}
+	return conn.Status == "LISTEN"
+}
+
+// SelectorFields lists the process fields accepted by ByField
+var SelectorFields = []string{"name", "exe", "cwd", "env", "user", "arg0"}
+
+// ByField returns PIDs whose field matches value. The whole field has to match:
+// value is an anchored regex, or an exact string if strict is true.
+// For env, value is KEY=VALUE (or just KEY to match any value). Paths given for
+// exe and cwd may start with ~ and are also compared with symlinks resolved.
+// arg0 matches either the first argument or its base name.
+func ByField(field, value string, strict bool) ([]int, error) {
+	var envKey string
+	var anyValue bool
+	switch field {
+	case "env":
+		key, envValue, hasValue := strings.Cut(value, "=")
+		envKey, value, anyValue = key, envValue, !hasValue
+	case "exe", "cwd":
+		value = expandHome(value)
+	case "name", "user", "arg0":
+	default:
+		return nil, fmt.Errorf("unknown field '%s' (available: %s)", field, strings.Join(SelectorFields, ", "))
+	}
+
+	candidates := []string{value}
+	if field == "exe" || field == "cwd" {
+		if resolved, err := filepath.EvalSymlinks(value); err == nil && resolved != value {
+			candidates = append(candidates, resolved)
+		}
+	}
+	var matchers []func(string) bool
+	for i, candidate := range candidates {
+		// A resolved path is always compared literally
+		matcher, err := fieldMatcher(candidate, strict || i > 0)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	matches := func(s string) bool {
+		for _, matcher := range matchers {
+			if matcher(s) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	currentPid := int32(os.Getpid())
+	for _, proc := range procs {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		// Skip the current process (psjungle itself)
+		if proc.Pid == currentPid {
+			continue
+		}
+
+		matched := false
+		switch field {
+		case "name":
+			name, err := proc.NameWithContext(ctx)
+			matched = err == nil && matches(name)
+		case "exe":
+			exe, err := proc.ExeWithContext(ctx)
+			matched = err == nil && exe != "" && matches(exe)
+		case "cwd":
+			cwd, err := proc.CwdWithContext(ctx)
+			matched = err == nil && cwd != "" && matches(cwd)
+		case "user":
+			username, err := proc.UsernameWithContext(ctx)
+			matched = err == nil && matches(username)
+		case "arg0":
+			args, err := proc.CmdlineSliceWithContext(ctx)
+			matched = err == nil && len(args) > 0 && (matches(args[0]) || matches(filepath.Base(args[0])))
+		case "env":
+			environ, err := proc.EnvironWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			for _, entry := range environ {
+				key, entryValue, _ := strings.Cut(entry, "=")
+				if key == envKey && (anyValue || matches(entryValue)) {
+					matched = true
+					break
+				}
+			}
+		}
+
+		if matched {
+			pids = append(pids, int(proc.Pid))
+		}
+	}
+
+	sort.Ints(pids)
+	return pids, nil
+}
+
+// fieldMatcher returns a function reporting whether a whole field matches
+// value, as an anchored regex or, if strict is true, an exact string
+func fieldMatcher(value string, strict bool) (func(string) bool, error) {
+	if strict {
+		return func(s string) bool { return s == value }, nil
+	}
+	// Compile the value on its own first so errors don't show the anchors
+	if _, err := regexp.Compile(value); err != nil {
+		return nil, err
+	}
+	re := regexp.MustCompile("^(?:" + value + ")$")
+	return re.MatchString, nil
+}
+
+// expandHome replaces a leading ~ with the home directory of the current user
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// ByUnit returns PIDs of the processes in the cgroup of a systemd unit, such as nginx.service.
+// A name without a type is a service, and shell patterns like "php*-fpm" match several units.
+// Slices, such as user-1000.slice, match every process below them. Only supported on Linux.
+func ByUnit(name string) ([]int, error) {
+	if !cgroupsSupported {
+		return nil, fmt.Errorf("unit: targets are only supported on Linux")
+	}
+	name = unitName(name)
+	if _, err := filepath.Match(name, ""); err != nil {
+		return nil, fmt.Errorf("invalid unit pattern '%s'", name)
+	}
+
+	pids, err := AllPids()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []int
+	for _, pid := range pids {
+		if unitMatches(name, processCgroup(int32(pid))) {
+			matches = append(matches, pid)
+		}
+	}
+	return matches, nil
+}
+
+// unitMatches reports whether a process in the cgroup path belongs to a unit matching
+// the pattern, completed by unitName
+func unitMatches(pattern, path string) bool {
+	unit, _ := parseUnit(path)
+	if matched, _ := filepath.Match(pattern, unit); matched {
+		return true
+	}
+	// Slices contain other slices and units, so every level of the path is checked
+	if strings.HasSuffix(pattern, ".slice") {
+		for _, segment := range strings.Split(path, "/") {
+			if matched, _ := filepath.Match(pattern, segment); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ByNamespace returns PIDs of the processes in the PID namespace with the given inode,
+// as shown by ls -l /proc/<pid>/ns/pid. With a non-zero nspid, only the process with
+// that PID inside the namespace is returned, to map PIDs found in container logs to the host.
+// Only supported on Linux.
+func ByNamespace(inode uint64, nspid int) ([]int, error) {
+	if !pidNamespacesSupported {
+		return nil, fmt.Errorf("ns: targets are only supported on Linux")
+	}
+
+	pids, err := AllPids()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []int
+	for _, pid := range pids {
+		if ns, ok := pidNamespace(int32(pid)); !ok || ns != inode {
+			continue
+		}
+		if nspid != 0 {
+			// A process in psjungle's own namespace has the same PID inside it
+			inner := innermostPid(namespacedPids(int32(pid)))
+			if inner == 0 {
+				inner = int32(pid)
+			}
+			if int(inner) != nspid {
+				continue
+			}
+		}
+		matches = append(matches, pid)
+	}
+	return matches, nil
+}
+
+// ByFile returns PIDs that have the given file open.
+// The path is made absolute and symlinks are resolved before comparing.
+func ByFile(path string) ([]int, error) {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []int
+	currentPid := int32(os.Getpid())
+	for _, proc := range procs {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		// Skip the current process (psjungle itself)
+		if proc.Pid == currentPid {
+			continue
+		}
+
+		files, err := proc.OpenFilesWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			// Deleted files are still held open under their old path
+			if strings.TrimSuffix(f.Path, " (deleted)") == target {
+				matches = append(matches, int(proc.Pid))
+				break
+			}
+		}
+	}
+
+	sort.Ints(matches)
+	return matches, nil
+}
+
 func gatherConnections(ctx context.Context) ([]gonet.ConnectionStat, error) {
 	if conns, err := gonet.ConnectionsWithContext(ctx, "inet"); err == nil {
 		return conns, nil
@@ -0,0 +1,30 @@
+package psjungle
+
+import (
+	"testing"
+
+	gonet "github.com/shirou/gopsutil/v3/net"
+)
+
+func TestMatchesPort(t *testing.T) {
+	wanted := map[uint32]bool{0: true, 80: true}
+	tests := []struct {
+		name   string
+		local  uint32
+		remote uint32
+		want   bool
+	}{
+		{"listener on a wanted port", 80, 0, true},
+		{"listener on another port", 8080, 0, false},
+		{"client of a wanted port", 51234, 80, true},
+		{"unbound socket", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := gonet.ConnectionStat{Laddr: gonet.Addr{Port: tt.local}, Raddr: gonet.Addr{Port: tt.remote}}
+			if got := matchesPort(wanted, conn); got != tt.want {
+				t.Errorf("matchesPort(%d -> %d) = %v, want %v", tt.local, tt.remote, got, tt.want)
+			}
+		})
+	}
+}
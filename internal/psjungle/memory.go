@@ -0,0 +1,41 @@
+package psjungle
+
+import "strings"
+
+// Memory measures accepted by --aggregate-mem
+const (
+	aggregateRSS = "rss"
+	aggregatePSS = "pss"
+)
+
+// proportionalColumns are the columns that need smaps to be read
+var proportionalColumns = []string{"pss", "uss"}
+
+// needsProportionalMemory reports whether PSS and USS have to be read for the selected
+// columns, --format template or --aggregate-mem. Reading smaps is much slower than the
+// other fields, so it is only done when they are shown.
+func needsProportionalMemory(opts *options, formatText string) bool {
+	if opts.aggregateMem == aggregatePSS {
+		return true
+	}
+	for _, name := range opts.columns {
+		for _, proportional := range proportionalColumns {
+			if name == proportional {
+				return true
+			}
+		}
+	}
+	return strings.Contains(formatText, ".PSS") || strings.Contains(formatText, ".USS")
+}
+
+// attachProportionalMemory reads the PSS and USS of every node in the trees
+func attachProportionalMemory(trees []*targetTree) {
+	for _, t := range trees {
+		for _, node := range appendNodes(nil, t.root) {
+			getProcessInfo(node)
+			var ok bool
+			node.info.PSS, node.info.USS, ok = proportionalMemory(node.Process.Pid)
+			node.info.pssUnread = !ok
+		}
+	}
+}
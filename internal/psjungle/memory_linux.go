@@ -0,0 +1,55 @@
+package psjungle
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// proportionalMemorySupported reports whether PSS and USS can be read on this platform
+const proportionalMemorySupported = true
+
+// proportionalMemory returns the PSS (resident memory with shared pages divided among
+// the processes sharing them) and USS (memory private to the process) of a process in
+// bytes. It reads /proc/<pid>/smaps_rollup, or sums /proc/<pid>/smaps on kernels older
+// than 4.14. Both are only readable for processes of the same user unless running as root.
+func proportionalMemory(pid int32) (pss, uss uint64, ok bool) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/smaps_rollup", pid))
+	if err != nil {
+		f, err = os.Open(fmt.Sprintf("/proc/%d/smaps", pid))
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		switch key {
+		case "Pss", "Private_Clean", "Private_Dirty":
+		default:
+			continue
+		}
+
+		// Values are in kB, e.g. "Pss:                1234 kB"
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if key == "Pss" {
+			pss += kb * 1024
+		} else {
+			uss += kb * 1024
+		}
+	}
+	if scanner.Err() != nil {
+		return 0, 0, false
+	}
+	return pss, uss, true
+}
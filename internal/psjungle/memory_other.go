@@ -0,0 +1,11 @@
+//go:build !linux
+
+package psjungle
+
+// proportionalMemorySupported reports whether PSS and USS can be read on this platform
+const proportionalMemorySupported = false
+
+// proportionalMemory is not available on this platform
+func proportionalMemory(pid int32) (pss, uss uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,65 @@
+package psjungle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseNamespaceInode parses a namespace inode given as a number or as the target of a
+// /proc/<pid>/ns/pid link, such as "pid:[4026532345]"
+func parseNamespaceInode(value string) (uint64, error) {
+	value = strings.TrimPrefix(value, "pid:")
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	inode, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || inode == 0 {
+		return 0, fmt.Errorf("invalid namespace '%s' (expected the inode of a PID namespace, e.g. 4026532345)", value)
+	}
+	return inode, nil
+}
+
+// parseNamespaceTarget parses the part of a ns: target after the prefix: a namespace
+// inode, optionally followed by :PID for one process by its PID inside the namespace
+func parseNamespaceTarget(spec string) (inode uint64, nspid int, err error) {
+	// The inode may be given as pid:[4026532345], so the PID is split off at the last colon
+	if i := strings.LastIndex(spec, ":"); i >= 0 && !strings.HasSuffix(spec[:i], "pid") {
+		nspid, err = strconv.Atoi(spec[i+1:])
+		if err != nil || nspid < 1 {
+			return 0, 0, fmt.Errorf("invalid namespaced PID '%s'", spec[i+1:])
+		}
+		spec = spec[:i]
+	}
+	inode, err = parseNamespaceInode(spec)
+	return inode, nspid, err
+}
+
+// innermostPid returns the PID of a process inside its innermost PID namespace,
+// or 0 when it lives in psjungle's own namespace
+func innermostPid(nspids []int32) int32 {
+	if len(nspids) < 2 {
+		return 0
+	}
+	return nspids[len(nspids)-1]
+}
+
+// needsNamespaces reports whether the PID namespaces of every process have to be read:
+// for JSON, or the pid, nspid and pidns columns and the matching --format fields
+func needsNamespaces(opts *options, formatText string) bool {
+	if opts.jsonOutput() || showsColumn(opts, "pid", "nspid", "pidns") {
+		return true
+	}
+	return strings.Contains(formatText, ".NSpid") || strings.Contains(formatText, ".PidNamespace")
+}
+
+// attachNamespaces reads the namespaced PIDs and the PID namespace of every node in the trees
+func attachNamespaces(trees []*targetTree) {
+	for _, t := range trees {
+		for _, node := range appendNodes(nil, t.root) {
+			getProcessInfo(node)
+			if nspids := namespacedPids(node.Process.Pid); len(nspids) > 1 {
+				node.info.NSpid = nspids
+			}
+			node.info.PidNamespace, _ = pidNamespace(node.Process.Pid)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package psjungle
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// pidNamespacesSupported reports whether PID namespaces can be read on this platform
+const pidNamespacesSupported = true
+
+// namespacedPids returns the PIDs of a process in each PID namespace it is visible in,
+// from the NSpid line of /proc/<pid>/status: first the PID seen by psjungle, last the
+// PID inside the innermost namespace. Processes in psjungle's own namespace have one entry.
+func namespacedPids(pid int32) []int32 {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "NSpid:")
+		if !ok {
+			continue
+		}
+		var pids []int32
+		for _, field := range strings.Fields(value) {
+			nspid, err := strconv.ParseInt(field, 10, 32)
+			if err != nil {
+				return nil
+			}
+			pids = append(pids, int32(nspid))
+		}
+		return pids
+	}
+	return nil
+}
+
+// pidNamespace returns the inode of the PID namespace of a process, from the
+// /proc/<pid>/ns/pid link such as "pid:[4026532345]"
+func pidNamespace(pid int32) (uint64, bool) {
+	link, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	if err != nil {
+		return 0, false
+	}
+	inode, err := parseNamespaceInode(link)
+	return inode, err == nil
+}
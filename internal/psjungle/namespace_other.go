@@ -0,0 +1,16 @@
+//go:build !linux
+
+package psjungle
+
+// pidNamespacesSupported reports whether PID namespaces can be read on this platform
+const pidNamespacesSupported = false
+
+// namespacedPids is not available on this platform
+func namespacedPids(pid int32) []int32 {
+	return nil
+}
+
+// pidNamespace is not available on this platform
+func pidNamespace(pid int32) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,272 @@
+package psjungle
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Output formats supported by the --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// options holds the command line settings that control how processes are
+// looked up and how their trees are rendered
+type options struct {
+	flatMode   bool
+	strictMode bool
+	portQuery  PortQuery // template for :port inputs, without the ports
+	owner      *ownerFilter
+	container  *containerFilter
+	where      *Where
+	top        *topSelection
+	output     string
+	watch      bool
+	columns    []string
+	format     *template.Template
+	sortKey    string
+	reverse    bool
+	sockets    bool
+	files      bool
+	aggregate  bool
+	threads    bool
+	byCgroup   bool
+	cpu        *cpuSampler
+	changes    *changeTracker
+
+	// aggregateMem is the memory summed by --aggregate, rss or pss
+	aggregateMem string
+	// proportional is set when PSS and USS have to be read, see needsProportionalMemory
+	proportional bool
+	// cgroups and namespaces are set when those fields have to be read, see needsCgroups
+	// and needsNamespaces
+	cgroups    bool
+	namespaces bool
+	// killTree makes -k signal the whole subtree of each match, in killOrder
+	killTree  bool
+	killOrder string
+	// killGrace is how long --kill-grace waits for the signaled processes before SIGKILL
+	killGrace time.Duration
+}
+
+// parseOptions reads the options from the CLI context and validates them
+func parseOptions(c *cli.Context) (*options, error) {
+	opts := &options{
+		flatMode:   c.Bool("flat"),
+		strictMode: c.Bool("strict"),
+		output:     strings.ToLower(c.String("output")),
+		watch:      c.IsSet("watch"),
+		sortKey:    strings.ToLower(c.String("sort")),
+		reverse:    c.Bool("reverse"),
+		sockets:    c.Bool("sockets"),
+		files:      c.Bool("files"),
+		aggregate:  c.Bool("aggregate"),
+		threads:    c.Bool("threads"),
+		byCgroup:   c.Bool("group-by-cgroup"),
+		cpu:        newCPUSampler(c.Duration("sample")),
+	}
+	opts.cpu.watch = opts.watch
+
+	if _, ok := childOrders[opts.sortKey]; !ok {
+		return nil, fmt.Errorf("invalid sort key '%s' (expected cpu, mem, pid, start or name)", opts.sortKey)
+	}
+
+	opts.portQuery = PortQuery{
+		Host:   c.String("host"),
+		Proto:  strings.ToLower(c.String("proto")),
+		Remote: c.Bool("remote"),
+	}
+
+	// Catch an invalid --host before any lookup is done
+	if opts.portQuery.Host != "" {
+		host, err := parseHostFilter(opts.portQuery.Host)
+		if err != nil {
+			return nil, err
+		}
+		// The remote end of a connection is never bound to all interfaces
+		if host.wildcard && opts.portQuery.Remote {
+			return nil, fmt.Errorf("--host %s cannot be used with --remote, remote addresses are never a wildcard", opts.portQuery.Host)
+		}
+	}
+
+	switch opts.portQuery.Proto {
+	case "", "tcp", "udp", "tcp6", "udp6":
+	default:
+		return nil, fmt.Errorf("invalid protocol '%s' (expected tcp, udp, tcp6 or udp6)", opts.portQuery.Proto)
+	}
+
+	if c.IsSet("state") {
+		state, err := parseConnectionState(c.String("state"))
+		if err != nil {
+			return nil, err
+		}
+		opts.portQuery.State = state
+	}
+
+	switch opts.output {
+	case "":
+		opts.output = outputText
+	case outputText, outputJSON:
+	default:
+		return nil, fmt.Errorf("invalid output format '%s' (expected text or json)", opts.output)
+	}
+
+	// Changes between refreshes are only highlighted in the text view
+	if opts.watch && !opts.jsonOutput() {
+		opts.changes = newChangeTracker()
+	}
+
+	owner, err := parseOwnerFilter(c.String("user"), c.String("uid"), c.String("group"))
+	if err != nil {
+		return nil, err
+	}
+	opts.owner = owner
+
+	container, err := parseContainerFilter(c.String("container"))
+	if err != nil {
+		return nil, err
+	}
+	opts.container = container
+	if opts.byCgroup && !cgroupsSupported {
+		return nil, fmt.Errorf("--group-by-cgroup is only supported on Linux")
+	}
+	if opts.threads && !threadsSupported {
+		return nil, fmt.Errorf("--threads is only supported on Linux")
+	}
+
+	if c.IsSet("where") {
+		where, err := CompileWhere(c.String("where"))
+		if err != nil {
+			return nil, err
+		}
+		// The trees reuse the CPU% sampled for the selection instead of waiting again
+		where.sampler = opts.cpu
+		opts.where = where
+	}
+
+	if c.IsSet("top") || c.IsSet("by") {
+		top := &topSelection{
+			n:       c.Int("top"),
+			by:      strings.ToLower(c.String("by")),
+			sampler: opts.cpu,
+		}
+		if !c.IsSet("top") || top.n < 1 {
+			return nil, fmt.Errorf("--top requires a number of processes greater than 0")
+		}
+		if top.by != "cpu" && top.by != "mem" {
+			return nil, fmt.Errorf("invalid --by '%s' (expected %s)", top.by, strings.Join(topKeys, " or "))
+		}
+		opts.top = top
+	}
+
+	if c.IsSet("columns") && c.IsSet("format") {
+		return nil, fmt.Errorf("--columns and --format cannot be used together")
+	}
+
+	if c.IsSet("columns") {
+		names, err := parseColumns(c.String("columns"))
+		if err != nil {
+			return nil, err
+		}
+		opts.columns = names
+	}
+
+	if c.IsSet("format") {
+		tmpl, err := parseFormat(c.String("format"))
+		if err != nil {
+			return nil, err
+		}
+		opts.format = tmpl
+	}
+
+	opts.aggregateMem = strings.ToLower(c.String("aggregate-mem"))
+	if opts.aggregateMem != aggregateRSS && opts.aggregateMem != aggregatePSS {
+		return nil, fmt.Errorf("invalid --aggregate-mem '%s' (expected rss or pss)", opts.aggregateMem)
+	}
+	if c.IsSet("aggregate-mem") && !opts.aggregate {
+		return nil, fmt.Errorf("--aggregate-mem requires --aggregate")
+	}
+	if opts.aggregateMem == aggregatePSS && !proportionalMemorySupported {
+		return nil, fmt.Errorf("--aggregate-mem pss is only supported on Linux")
+	}
+	opts.proportional = needsProportionalMemory(opts, c.String("format"))
+	opts.cgroups = needsCgroups(opts, c.String("format"))
+	opts.namespaces = needsNamespaces(opts, c.String("format"))
+
+	opts.killTree = c.Bool("kill-tree")
+	opts.killOrder = strings.ToLower(c.String("kill-order"))
+	if opts.killOrder != killOrderRoot && opts.killOrder != killOrderLeaves {
+		return nil, fmt.Errorf("invalid --kill-order '%s' (expected root or leaves)", opts.killOrder)
+	}
+	if c.IsSet("kill-order") && !opts.killTree {
+		return nil, fmt.Errorf("--kill-order requires --kill-tree")
+	}
+	if c.IsSet("kill-grace") {
+		opts.killGrace = c.Duration("kill-grace")
+		if opts.killGrace <= 0 {
+			return nil, fmt.Errorf("--kill-grace requires a duration greater than 0, e.g. 10s")
+		}
+		if opts.watch {
+			return nil, fmt.Errorf("--kill-grace cannot be used with --watch")
+		}
+	}
+	// --kill-tree only widens what -k signals, it never sends a signal on its own
+	if opts.killTree && !c.IsSet("kill") && opts.killGrace == 0 {
+		return nil, fmt.Errorf("--kill-tree requires -k or --kill-grace")
+	}
+
+	return opts, nil
+}
+
+// parseConnectionState accepts a connection state in any case, with - or _ between words
+func parseConnectionState(value string) (string, error) {
+	state := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(value), "-", "_"))
+	for _, s := range ConnectionStates {
+		if s == state {
+			return state, nil
+		}
+	}
+	return "", fmt.Errorf("invalid connection state '%s' (expected one of %s)", value, strings.ToLower(strings.Join(ConnectionStates, ", ")))
+}
+
+// predicates returns the filters that narrow down the processes matched by the inputs
+func (o *options) predicates() []Predicate {
+	var preds []Predicate
+	if o.owner != nil {
+		preds = append(preds, o.owner)
+	}
+	if o.container != nil {
+		preds = append(preds, o.container)
+	}
+	if o.where != nil {
+		preds = append(preds, o.where)
+	}
+	return preds
+}
+
+// selectsWithoutInputs reports whether processes can be selected without any
+// PID, port or pattern: filters and --top then look at every process
+func (o *options) selectsWithoutInputs() bool {
+	return len(o.predicates()) > 0 || o.top != nil
+}
+
+// jsonOutput reports whether trees should be serialized as JSON
+func (o *options) jsonOutput() bool {
+	return o.output == outputJSON
+}
+
+// messages returns the writer used for status and warning messages.
+// When JSON is written to stdout, messages go to stderr so the output stays parseable.
+func (o *options) messages() io.Writer {
+	if o.jsonOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
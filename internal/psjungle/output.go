@@ -0,0 +1,142 @@
+package psjungle
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// processInfo holds the per-process fields that are rendered for a tree node.
+// It is also the data passed to --format templates.
+type processInfo struct {
+	Pid      int32     `json:"pid"`
+	Ppid     int32     `json:"ppid"`
+	User     string    `json:"user"`
+	Name     string    `json:"name"`
+	Cmdline  string    `json:"cmdline"`
+	CPU      float64   `json:"cpu"`
+	RSS      uint64    `json:"rss"`
+	VSZ      uint64    `json:"vsz"`
+	PSS      uint64    `json:"pss,omitempty"`
+	USS      uint64    `json:"uss,omitempty"`
+	Threads  int32     `json:"threads"`
+	Start    time.Time `json:"start"`
+	IsTarget bool      `json:"isTarget"`
+
+	// Cgroup, Container, Unit and Slice are only known on Linux. They are read for every
+	// node with -o json, otherwise only when a column, --format or --group-by-cgroup
+	// shows them (see needsCgroups)
+	Cgroup    string         `json:"cgroup,omitempty"`
+	Container *containerInfo `json:"container,omitempty"`
+	Unit      string         `json:"unit,omitempty"`
+	Slice     string         `json:"slice,omitempty"`
+
+	// NSpid lists the PIDs of a process in nested PID namespaces, from the one seen by
+	// psjungle to the innermost, and is only set for processes in another namespace.
+	// Both are only read on Linux, for every node with -o json and otherwise when a
+	// column or --format shows them (see needsNamespaces)
+	NSpid        []int32 `json:"nspid,omitempty"`
+	PidNamespace uint64  `json:"pidNamespace,omitempty"`
+
+	// PSS and USS are only read on Linux when a pss or uss column or --aggregate-mem pss needs them
+	// (see attachProportionalMemory). Sockets and Files are only filled in with --sockets and --files
+	Sockets []socketInfo `json:"sockets,omitempty"`
+	Files   []fileInfo   `json:"files,omitempty"`
+
+	// Tasks are only filled in with --threads, for multi-threaded processes
+	Tasks []threadInfo `json:"tasks,omitempty"`
+
+	// Total is only filled in with --aggregate, for the target and its descendants with children
+	Total *subtreeTotal `json:"total,omitempty"`
+
+	// pssUnread is set when PSS and USS were needed but could not be read, e.g. for
+	// other users' processes without root
+	pssUnread bool
+}
+
+// getProcessInfo returns the displayable fields for a tree node.
+// The fields are collected on first use and cached on the node.
+func getProcessInfo(node *ProcessNode) processInfo {
+	if node.info == nil {
+		info := collectProcessInfo(node)
+		node.info = &info
+	}
+	return *node.info
+}
+
+// collectProcessInfo queries the process for the displayable fields of a tree node
+func collectProcessInfo(node *ProcessNode) processInfo {
+	name, _ := node.Process.Name()
+	cmdline, _ := node.Process.Cmdline()
+	ppid, _ := node.Process.Ppid()
+	cpuPercent, _ := node.Process.CPUPercent()
+	username, _ := node.Process.Username()
+	threads, _ := node.Process.NumThreads()
+	memInfo, _ := node.Process.MemoryInfo()
+	var rss, vsz uint64
+	if memInfo != nil {
+		rss = memInfo.RSS
+		vsz = memInfo.VMS
+	}
+	var start time.Time
+	if createTime, err := node.Process.CreateTime(); err == nil {
+		start = time.UnixMilli(createTime)
+	}
+
+	if cmdline == "" {
+		cmdline = name
+	}
+
+	return processInfo{
+		Pid:      node.Process.Pid,
+		Ppid:     ppid,
+		User:     username,
+		Name:     name,
+		Cmdline:  filterMacOSKernelDetails(cmdline),
+		CPU:      cpuPercent,
+		RSS:      rss,
+		VSZ:      vsz,
+		Threads:  threads,
+		Start:    start,
+		IsTarget: node.IsTarget,
+	}
+}
+
+// jsonNode is the JSON representation of a ProcessNode and its children
+type jsonNode struct {
+	processInfo
+	Children []*jsonNode `json:"children"`
+}
+
+// jsonTree is the JSON representation of a tree displayed for a target PID
+type jsonTree struct {
+	Target int       `json:"target"`
+	Tree   *jsonNode `json:"tree"`
+}
+
+// toJSONNode converts a process tree into its JSON representation
+func toJSONNode(node *ProcessNode) *jsonNode {
+	out := &jsonNode{
+		processInfo: getProcessInfo(node),
+		Children:    []*jsonNode{},
+	}
+	for _, child := range node.Children {
+		out.Children = append(out.Children, toJSONNode(child))
+	}
+	return out
+}
+
+// writeJSONTrees serializes the displayed trees as a JSON array.
+// Indented output is used for one-shot runs, compact single-line output for watch mode.
+func writeJSONTrees(w io.Writer, trees []*targetTree, indent bool) error {
+	out := make([]*jsonTree, 0, len(trees))
+	for _, t := range trees {
+		out = append(out, &jsonTree{Target: t.pid, Tree: toJSONNode(t.root)})
+	}
+
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(out)
+}
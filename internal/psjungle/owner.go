@@ -0,0 +1,121 @@
+package psjungle
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ownerFilter narrows the matched processes to the given owners. A process
+// matches when its effective UID is one of uids (if any) and its effective GID
+// is one of gids (if any), like ps -u.
+type ownerFilter struct {
+	uids map[int32]bool
+	gids map[int32]bool
+
+	// args are the flags the filter was built from, for the watch status line
+	args string
+}
+
+// parseOwnerFilter builds the filter from the comma-separated --user, --uid and
+// --group values. It returns nil when none of them is set.
+func parseOwnerFilter(users, uids, groups string) (*ownerFilter, error) {
+	f := &ownerFilter{
+		uids: make(map[int32]bool),
+		gids: make(map[int32]bool),
+	}
+
+	for _, name := range splitList(users) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown user '%s'", name)
+		}
+		uid, err := strconv.ParseInt(u.Uid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("user '%s' has a non-numeric UID '%s'", name, u.Uid)
+		}
+		f.uids[int32(uid)] = true
+	}
+
+	for _, value := range splitList(uids) {
+		uid, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || uid < 0 {
+			return nil, fmt.Errorf("invalid UID '%s'", value)
+		}
+		f.uids[int32(uid)] = true
+	}
+
+	// Groups can be given by name or GID
+	for _, name := range splitList(groups) {
+		gidText := name
+		if _, err := strconv.Atoi(name); err != nil {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return nil, fmt.Errorf("unknown group '%s'", name)
+			}
+			gidText = g.Gid
+		}
+		gid, err := strconv.ParseInt(gidText, 10, 32)
+		if err != nil || gid < 0 {
+			return nil, fmt.Errorf("invalid group '%s'", name)
+		}
+		f.gids[int32(gid)] = true
+	}
+
+	if len(f.uids) == 0 && len(f.gids) == 0 {
+		return nil, nil
+	}
+
+	for _, flag := range [][2]string{{"user", users}, {"uid", uids}, {"group", groups}} {
+		if flag[1] != "" {
+			f.args += fmt.Sprintf(" --%s %s", flag[0], flag[1])
+		}
+	}
+	return f, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Match reports whether the process is owned by one of the filter's users and groups
+func (f *ownerFilter) Match(proc *process.Process) bool {
+	if len(f.uids) > 0 {
+		uids, err := proc.Uids()
+		uid, ok := effectiveID(uids)
+		if err != nil || !ok || !f.uids[uid] {
+			return false
+		}
+	}
+	if len(f.gids) > 0 {
+		gids, err := proc.Gids()
+		gid, ok := effectiveID(gids)
+		if err != nil || !ok || !f.gids[gid] {
+			return false
+		}
+	}
+	return true
+}
+
+// effectiveID picks the effective ID out of what Uids or Gids returned. Linux
+// lists the real, effective, saved and filesystem IDs, while macOS only has the
+// effective one.
+func effectiveID(ids []int32) (int32, bool) {
+	switch {
+	case len(ids) > 1:
+		return ids[1], true
+	case len(ids) == 1:
+		return ids[0], true
+	}
+	return 0, false
+}
@@ -0,0 +1,24 @@
+package psjungle
+
+import "testing"
+
+func TestEffectiveID(t *testing.T) {
+	tests := []struct {
+		name   string
+		ids    []int32
+		want   int32
+		wantOK bool
+	}{
+		{"real, effective, saved and filesystem IDs", []int32{1000, 0, 0, 0}, 0, true},
+		{"effective ID only", []int32{501}, 501, true},
+		{"no IDs", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := effectiveID(tt.ids)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("effectiveID(%v) = %d, %v, want %d, %v", tt.ids, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package psjungle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	gonet "github.com/shirou/gopsutil/v3/net"
+)
+
+// socketInfo describes one socket held by a process, as shown by --sockets
+type socketInfo struct {
+	Proto  string `json:"proto"`
+	Local  string `json:"local"`
+	Remote string `json:"remote,omitempty"`
+	State  string `json:"state,omitempty"`
+}
+
+// newSocketInfo converts a connection reported by gopsutil
+func newSocketInfo(conn gonet.ConnectionStat) socketInfo {
+	info := socketInfo{
+		Proto: connProto(conn),
+		Local: formatSocketAddr(conn.Laddr),
+	}
+	// Listening and unconnected sockets report a zero remote address
+	if conn.Raddr.Port != 0 {
+		info.Remote = formatSocketAddr(conn.Raddr)
+	}
+	// UDP sockets have no state; gopsutil reports them as NONE
+	if conn.Status != "" && conn.Status != "NONE" {
+		info.State = conn.Status
+	}
+	return info
+}
+
+// formatSocketAddr formats an address as host:port, with IPv6 hosts in brackets
+func formatSocketAddr(addr gonet.Addr) string {
+	host := addr.IP
+	if host == "" {
+		host = "*"
+	}
+	return net.JoinHostPort(host, fmt.Sprintf("%d", addr.Port))
+}
+
+// String formats the socket like lsof: proto, local address, remote address and state
+func (s socketInfo) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", s.Proto, s.Local)
+	if s.Remote != "" {
+		fmt.Fprintf(&b, " -> %s", s.Remote)
+	}
+	if s.State != "" {
+		fmt.Fprintf(&b, " (%s)", s.State)
+	}
+	return b.String()
+}
+
+// attachSockets looks up the sockets of every node in the trees. The connection
+// table is read once and grouped by PID.
+func attachSockets(trees []*targetTree, w io.Writer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conns, err := gatherConnections(ctx)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: Could not list sockets: %v\n", err)
+		return
+	}
+
+	byPid := make(map[int32][]socketInfo)
+	for _, conn := range conns {
+		if conn.Pid == 0 {
+			continue
+		}
+		byPid[conn.Pid] = append(byPid[conn.Pid], newSocketInfo(conn))
+	}
+
+	for _, t := range trees {
+		for _, node := range appendNodes(nil, t.root) {
+			getProcessInfo(node)
+			sockets := byPid[node.Process.Pid]
+			sortSockets(sockets)
+			node.info.Sockets = sockets
+		}
+	}
+}
+
+// sortSockets puts listening sockets first, then orders by protocol and local address
+func sortSockets(sockets []socketInfo) {
+	sort.SliceStable(sockets, func(i, j int) bool {
+		a, b := sockets[i], sockets[j]
+		if (a.State == "LISTEN") != (b.State == "LISTEN") {
+			return a.State == "LISTEN"
+		}
+		if a.Proto != b.Proto {
+			return a.Proto < b.Proto
+		}
+		if a.Local != b.Local {
+			return a.Local < b.Local
+		}
+		return a.Remote < b.Remote
+	})
+}
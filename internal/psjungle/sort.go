@@ -0,0 +1,58 @@
+package psjungle
+
+import (
+	"sort"
+	"strings"
+)
+
+// childOrders maps the --sort keys to a "less" function for sibling processes.
+// cpu and mem order the heaviest processes first, the other keys ascending.
+var childOrders = map[string]func(a, b processInfo) bool{
+	"cpu": func(a, b processInfo) bool {
+		return a.CPU > b.CPU
+	},
+	"mem": func(a, b processInfo) bool {
+		return a.RSS > b.RSS
+	},
+	"pid": func(a, b processInfo) bool {
+		return a.Pid < b.Pid
+	},
+	"start": func(a, b processInfo) bool {
+		return a.Start.Before(b.Start)
+	},
+	"name": func(a, b processInfo) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	},
+}
+
+// sortTree orders the children of every node in the tree by the given key.
+// Ties are broken by PID so the order is stable between refreshes.
+func sortTree(node *ProcessNode, key string, reverse bool) {
+	less, ok := childOrders[key]
+	if !ok {
+		return
+	}
+
+	if node.info != nil {
+		sortThreads(node.info.Tasks, key, reverse)
+	}
+
+	children := node.Children
+	sort.SliceStable(children, func(i, j int) bool {
+		a, b := getProcessInfo(children[i]), getProcessInfo(children[j])
+		if reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Pid < b.Pid
+	})
+
+	for _, child := range children {
+		sortTree(child, key, reverse)
+	}
+}
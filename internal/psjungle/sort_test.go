@@ -0,0 +1,64 @@
+package psjungle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sortTestTree returns a process with four children whose order differs for every sort key
+func sortTestTree() *ProcessNode {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return testNode(processInfo{Pid: 1},
+		testNode(processInfo{Pid: 5, Name: "Bravo", CPU: 10, RSS: 300 << 20, Start: start.Add(3 * time.Second)}),
+		testNode(processInfo{Pid: 3, Name: "charlie", CPU: 50, RSS: 100 << 20, Start: start.Add(1 * time.Second)}),
+		testNode(processInfo{Pid: 7, Name: "alpha", CPU: 30, RSS: 200 << 20, Start: start.Add(2 * time.Second)}),
+		testNode(processInfo{Pid: 4, Name: "delta", CPU: 30, RSS: 50 << 20, Start: start.Add(4 * time.Second)}),
+	)
+}
+
+func TestSortTree(t *testing.T) {
+	tests := []struct {
+		key     string
+		reverse bool
+		want    []int32
+	}{
+		{"cpu", false, []int32{3, 4, 7, 5}},
+		{"cpu", true, []int32{5, 7, 4, 3}},
+		{"mem", false, []int32{5, 7, 3, 4}},
+		{"mem", true, []int32{4, 3, 7, 5}},
+		{"pid", false, []int32{3, 4, 5, 7}},
+		{"pid", true, []int32{7, 5, 4, 3}},
+		{"start", false, []int32{3, 7, 5, 4}},
+		{"start", true, []int32{4, 5, 7, 3}},
+		{"name", false, []int32{7, 5, 3, 4}},
+		{"name", true, []int32{4, 3, 5, 7}},
+		{"unknown", false, []int32{5, 3, 7, 4}},
+	}
+	for _, tt := range tests {
+		name := tt.key
+		if tt.reverse {
+			name += " reversed"
+		}
+		t.Run(name, func(t *testing.T) {
+			root := sortTestTree()
+			sortTree(root, tt.key, tt.reverse)
+			if got := childPids(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("children sorted by %s (reverse %v) = %v, want %v", tt.key, tt.reverse, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortTreeSortsDescendants(t *testing.T) {
+	root := testNode(processInfo{Pid: 1},
+		testNode(processInfo{Pid: 2},
+			testNode(processInfo{Pid: 9}),
+			testNode(processInfo{Pid: 8}),
+		),
+	)
+	sortTree(root, "pid", false)
+	if got := childPids(root.Children[0]); !reflect.DeepEqual(got, []int32{8, 9}) {
+		t.Errorf("grandchildren sorted by pid = %v, want [8 9]", got)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build linux || darwin
+
+package psjungle
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalState holds the terminal settings to restore after raw mode
+type terminalState struct {
+	termios unix.Termios
+}
+
+// isTerminal reports whether the file is connected to a terminal
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), ioctlReadTermios)
+	return err == nil
+}
+
+// makeRaw puts the terminal into raw mode and returns the previous state
+func makeRaw(f *os.File) (*terminalState, error) {
+	fd := int(f.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	oldState := &terminalState{termios: *termios}
+
+	// Same settings as cfmakeraw(3)
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, termios); err != nil {
+		return nil, err
+	}
+
+	return oldState, nil
+}
+
+// restoreTerminal restores the terminal settings saved by makeRaw
+func restoreTerminal(f *os.File, state *terminalState) error {
+	return unix.IoctlSetTermios(int(f.Fd()), ioctlWriteTermios, &state.termios)
+}
+
+// terminalSize returns the width and height of the terminal
+func terminalSize(f *os.File) (int, int, error) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
+
+// notifyResize delivers a signal on ch whenever the terminal is resized
+func notifyResize(ch chan<- os.Signal) {
+	signal.Notify(ch, unix.SIGWINCH)
+}
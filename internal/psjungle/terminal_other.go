@@ -0,0 +1,34 @@
+//go:build !linux && !darwin
+
+package psjungle
+
+import (
+	"errors"
+	"os"
+)
+
+// terminalState is a placeholder on platforms without raw terminal support
+type terminalState struct{}
+
+// isTerminal always reports false so watch mode falls back to plain refreshes
+func isTerminal(f *os.File) bool {
+	return false
+}
+
+// makeRaw is not supported on this platform
+func makeRaw(f *os.File) (*terminalState, error) {
+	return nil, errors.New("raw terminal mode not supported on this platform")
+}
+
+// restoreTerminal is not supported on this platform
+func restoreTerminal(f *os.File, state *terminalState) error {
+	return nil
+}
+
+// terminalSize is not supported on this platform
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errors.New("terminal size not supported on this platform")
+}
+
+// notifyResize is a no-op on platforms without SIGWINCH
+func notifyResize(ch chan<- os.Signal) {}
@@ -0,0 +1,77 @@
+package psjungle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// threadInfo describes one thread of a process for --threads
+type threadInfo struct {
+	Tid   int32   `json:"tid"`
+	Name  string  `json:"name"`
+	CPU   float64 `json:"cpu"`
+	State string  `json:"state"`
+
+	// pid and start identify the thread when its CPU% is sampled
+	pid   int32
+	start int64
+}
+
+// String formats a thread for a tree leaf like a process line: TID, CPU%, state and
+// the thread name in braces, as pstree does
+func (t threadInfo) String() string {
+	return fmt.Sprintf("%d %.1f %s {%s}", t.Tid, t.CPU, t.State, t.Name)
+}
+
+// attachThreads lists the threads of every multi-threaded process in the trees.
+// Single-threaded processes get no leaves, since their only thread is the process itself.
+func attachThreads(trees []*targetTree) {
+	for _, t := range trees {
+		for _, node := range appendNodes(nil, t.root) {
+			getProcessInfo(node)
+			if threads := listThreads(node.Process.Pid); len(threads) > 1 {
+				node.info.Tasks = threads
+			}
+		}
+	}
+}
+
+// sortThreads orders threads like their processes: by CPU% or name when sorting by
+// those keys, and by TID otherwise. Ties are broken by TID.
+func sortThreads(threads []threadInfo, key string, reverse bool) {
+	sort.SliceStable(threads, func(i, j int) bool {
+		a, b := threads[i], threads[j]
+		if reverse {
+			a, b = b, a
+		}
+		switch {
+		case key == "cpu" && a.CPU != b.CPU:
+			return a.CPU > b.CPU
+		case key == "name" && !strings.EqualFold(a.Name, b.Name):
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+		return a.Tid < b.Tid
+	})
+}
+
+// nodeThreads returns the thread leaves shown for a node with --threads
+func nodeThreads(node *ProcessNode, opts *options) []threadInfo {
+	// Exited processes are only shown for one refresh and have no threads anymore
+	if !opts.threads || node.exited {
+		return nil
+	}
+	return getProcessInfo(node).Tasks
+}
+
+// threadPrefix returns the tree prefix of a thread leaf of node. Threads come before
+// the child processes, so only the last thread of a node without children closes the branch.
+func threadPrefix(node *ProcessNode, nextSiblings []*ProcessNode, last bool, flatMode bool) string {
+	if flatMode {
+		return "    "
+	}
+	if last {
+		return childIndent(node, nextSiblings, flatMode) + "└── "
+	}
+	return childIndent(node, nextSiblings, flatMode) + "├── "
+}
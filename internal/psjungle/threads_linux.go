@@ -0,0 +1,113 @@
+package psjungle
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// threadsSupported reports whether threads can be listed on this platform
+const threadsSupported = true
+
+// clockTicks is USER_HZ, the unit of the CPU and start times in /proc/<pid>/task/<tid>/stat
+const clockTicks = 100
+
+// threadStat holds the fields of /proc/<pid>/task/<tid>/stat used for thread leaves
+type threadStat struct {
+	name    string
+	state   string
+	seconds float64 // user and system CPU time
+	start   int64   // clock ticks after boot
+}
+
+// readThreadStat reads the stat file of one thread. The per-thread file is needed:
+// /proc/<tid>/stat reports the totals of the whole process.
+func readThreadStat(pid, tid int32) (threadStat, bool) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/task/%d/stat", pid, tid))
+	if err != nil {
+		return threadStat{}, false
+	}
+
+	// The name is in parentheses and may itself contain spaces and parentheses
+	open := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open || end+2 > len(data) {
+		return threadStat{}, false
+	}
+
+	// fields[0] is the state (field 3 of stat), utime and stime are fields 14 and 15,
+	// and starttime is field 22
+	fields := strings.Fields(string(data[end+2:]))
+	if len(fields) < 20 {
+		return threadStat{}, false
+	}
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	start, err3 := strconv.ParseInt(fields[19], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return threadStat{}, false
+	}
+
+	return threadStat{
+		name:    string(data[open+1 : end]),
+		state:   fields[0],
+		seconds: float64(utime+stime) / clockTicks,
+		start:   start,
+	}, true
+}
+
+// listThreads returns the threads of a process, with their CPU% averaged over their lifetime
+func listThreads(pid int32) []threadInfo {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return nil
+	}
+	boot, _ := host.BootTime()
+
+	threads := make([]threadInfo, 0, len(entries))
+	for _, entry := range entries {
+		tid, err := strconv.ParseInt(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		stat, ok := readThreadStat(pid, int32(tid))
+		if !ok {
+			// The thread exited while listing
+			continue
+		}
+
+		var cpu float64
+		started := time.Unix(int64(boot), 0).Add(time.Duration(stat.start) * time.Second / clockTicks)
+		if elapsed := time.Since(started).Seconds(); boot > 0 && elapsed > 0 {
+			cpu = 100 * stat.seconds / elapsed
+		}
+
+		threads = append(threads, threadInfo{
+			Tid:   int32(tid),
+			Name:  stat.name,
+			CPU:   cpu,
+			State: stat.state,
+			pid:   pid,
+			start: stat.start,
+		})
+	}
+	return threads
+}
+
+// threadSnapshot reads the accumulated CPU time of a thread
+func threadSnapshot(pid, tid int32) (cpuSnapshot, bool) {
+	stat, ok := readThreadStat(pid, tid)
+	if !ok {
+		return cpuSnapshot{}, false
+	}
+	return cpuSnapshot{
+		seconds:   stat.seconds,
+		createdAt: stat.start,
+		takenAt:   time.Now(),
+	}, true
+}
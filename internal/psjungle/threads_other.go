@@ -0,0 +1,16 @@
+//go:build !linux
+
+package psjungle
+
+// threadsSupported reports whether threads can be listed on this platform
+const threadsSupported = false
+
+// listThreads is not available on this platform
+func listThreads(pid int32) []threadInfo {
+	return nil
+}
+
+// threadSnapshot is not available on this platform
+func threadSnapshot(pid, tid int32) (cpuSnapshot, bool) {
+	return cpuSnapshot{}, false
+}
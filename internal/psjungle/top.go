@@ -0,0 +1,61 @@
+package psjungle
+
+import (
+	"sort"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// topSelection picks the heaviest processes for --top
+type topSelection struct {
+	n  int
+	by string // cpu or mem
+
+	// sampler measures CPU% for --by cpu; it is shared with the trees
+	sampler *cpuSampler
+}
+
+// topKeys lists the values accepted by --by
+var topKeys = []string{"cpu", "mem"}
+
+// pick returns the n heaviest of pids, heaviest first. Ties are broken by PID.
+func (t *topSelection) pick(pids []int) []int {
+	var procs []*process.Process
+	for _, pid := range pids {
+		proc, err := process.NewProcess(int32(pid))
+		if err != nil {
+			continue
+		}
+		procs = append(procs, proc)
+	}
+
+	weights := make(map[int32]float64, len(procs))
+	if t.by == "cpu" && t.sampler.enabled() {
+		weights = t.sampler.percentages(procs)
+	} else {
+		for _, proc := range procs {
+			if t.by == "cpu" {
+				weights[proc.Pid], _ = proc.CPUPercent()
+			} else if mem, err := proc.MemoryInfo(); err == nil {
+				weights[proc.Pid] = float64(mem.RSS)
+			}
+		}
+	}
+
+	sort.SliceStable(procs, func(i, j int) bool {
+		a, b := weights[procs[i].Pid], weights[procs[j].Pid]
+		if a != b {
+			return a > b
+		}
+		return procs[i].Pid < procs[j].Pid
+	})
+
+	if len(procs) > t.n {
+		procs = procs[:t.n]
+	}
+	top := make([]int, len(procs))
+	for i, proc := range procs {
+		top[i] = int(proc.Pid)
+	}
+	return top
+}
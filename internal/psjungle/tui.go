@@ -0,0 +1,597 @@
+package psjungle
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Special keys decoded from terminal input. They are placed above the
+// Unicode range so they can share a rune channel with regular characters.
+const (
+	keyUp rune = unicode.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyCtrlC
+)
+
+// watchHelpText is shown in the footer of the interactive watch view
+const watchHelpText = "↑/↓ move  ←/→ collapse/expand  space toggle  s signal  / targets  +/- interval  r refresh  q quit"
+
+// uiRow is a single line of the interactive watch view
+type uiRow struct {
+	node  *ProcessNode // nil for tree headers and separators
+	text  string
+	style string
+}
+
+// uiPrompt is a single-line input shown in the footer
+type uiPrompt struct {
+	label  string
+	value  string
+	submit func(value string)
+}
+
+// watchUI is the interactive full-screen view used by watch mode on a terminal
+type watchUI struct {
+	inputs     []string
+	opts       *options
+	interval   time.Duration
+	killSignal syscall.Signal
+	killValue  string
+	useKill    bool
+
+	trees     []*targetTree
+	multiple  bool
+	rows      []uiRow
+	collapsed map[int32]bool
+	cursor    int
+	cursorPid int32
+	offset    int
+	width     int
+	height    int
+
+	message string
+	prompt  *uiPrompt
+	out     *bufio.Writer
+}
+
+// runWatchUI runs the interactive watch view until the user quits
+func runWatchUI(inputs []string, opts *options, interval time.Duration, killSignal syscall.Signal, useKill bool, killValue string) error {
+	state, err := makeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restoreTerminal(os.Stdin, state)
+
+	ui := &watchUI{
+		inputs:     inputs,
+		opts:       opts,
+		interval:   interval,
+		killSignal: killSignal,
+		killValue:  killValue,
+		useKill:    useKill,
+		collapsed:  make(map[int32]bool),
+		out:        bufio.NewWriter(os.Stdout),
+	}
+
+	// Switch to the alternate screen and hide the cursor while the view is active
+	ui.out.WriteString("\033[?1049h\033[?25l")
+	defer func() {
+		ui.out.WriteString("\033[?25h\033[?1049l")
+		ui.out.Flush()
+	}()
+
+	keys := make(chan rune, 16)
+	go readKeys(os.Stdin, keys)
+
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+	defer signal.Stop(resize)
+
+	ui.refresh()
+	ui.draw()
+
+	timer := time.NewTimer(ui.interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			quit, refreshNow := ui.handleKey(k)
+			if quit {
+				return nil
+			}
+			if refreshNow {
+				ui.refresh()
+				timer.Reset(ui.interval)
+			}
+			ui.draw()
+		case <-resize:
+			ui.draw()
+		case <-timer.C:
+			ui.refresh()
+			ui.draw()
+			timer.Reset(ui.interval)
+		}
+	}
+}
+
+// readKeys decodes terminal input into keys until stdin is closed
+func readKeys(f *os.File, keys chan<- rune) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// parseKeys decodes a chunk of raw terminal input, including common escape sequences
+func parseKeys(buf []byte) []rune {
+	sequences := map[string]rune{
+		"\033[A":  keyUp,
+		"\033[B":  keyDown,
+		"\033[C":  keyRight,
+		"\033[D":  keyLeft,
+		"\033OA":  keyUp,
+		"\033OB":  keyDown,
+		"\033OC":  keyRight,
+		"\033OD":  keyLeft,
+		"\033[5~": keyPageUp,
+		"\033[6~": keyPageDown,
+		"\033[H":  keyHome,
+		"\033[F":  keyEnd,
+		"\033[1~": keyHome,
+		"\033[4~": keyEnd,
+	}
+
+	var keys []rune
+	s := string(buf)
+	for len(s) > 0 {
+		if s[0] == '\033' {
+			matched := false
+			for seq, k := range sequences {
+				if strings.HasPrefix(s, seq) {
+					keys = append(keys, k)
+					s = s[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// A lone escape, or a sequence we don't know about
+				if len(s) == 1 {
+					keys = append(keys, keyEscape)
+				}
+				return keys
+			}
+			continue
+		}
+
+		r := []rune(s)[0]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case 127, '\b':
+			keys = append(keys, keyBackspace)
+		case 3:
+			keys = append(keys, keyCtrlC)
+		default:
+			keys = append(keys, r)
+		}
+		s = s[len(string(r)):]
+	}
+	return keys
+}
+
+// refresh re-resolves the inputs and rebuilds the trees
+func (ui *watchUI) refresh() {
+	allPids, err := parseInputs(ui.inputs, ui.opts)
+	if err != nil {
+		ui.trees = nil
+		ui.message = err.Error()
+		ui.buildRows()
+		return
+	}
+
+	var msgs bytes.Buffer
+	trees, processedPids := collectTrees(allPids, ui.opts, &msgs, make(map[int]bool))
+	if ui.useKill {
+		signalMatches(trees, processedPids, ui.killSignal, ui.opts, &msgs)
+	}
+
+	ui.trees = trees
+	ui.multiple = len(allPids) > 1
+	if len(allPids) == 0 {
+		ui.message = "No processes found"
+	} else if msgs.Len() > 0 {
+		lines := strings.Split(strings.TrimSpace(msgs.String()), "\n")
+		ui.message = lines[len(lines)-1]
+	}
+	ui.buildRows()
+}
+
+// buildRows flattens the visible part of the trees into rows and restores the cursor
+func (ui *watchUI) buildRows() {
+	ui.rows = ui.rows[:0]
+	groups := []*treeGroup{{trees: ui.trees}}
+	if ui.opts.byCgroup {
+		groups = groupTrees(ui.trees)
+	}
+	for _, group := range groups {
+		if group.label != "" {
+			if len(ui.rows) > 0 {
+				ui.rows = append(ui.rows, uiRow{})
+			}
+			ui.rows = append(ui.rows, uiRow{text: sanitizeLine(group.label + ":"), style: "\033[1m"})
+		}
+		for i, t := range group.trees {
+			if len(ui.rows) > 0 && (i > 0 || group.label == "") {
+				ui.rows = append(ui.rows, uiRow{})
+			}
+			if ui.multiple {
+				ui.rows = append(ui.rows, uiRow{text: fmt.Sprintf("Process tree for PID %d:", t.pid)})
+			}
+			ui.addRows(t.root, nil)
+		}
+	}
+
+	if exited := ui.opts.changes.exited; len(exited) > 0 {
+		ui.rows = append(ui.rows, uiRow{}, uiRow{text: "Exited since last refresh:"})
+		for _, info := range exited {
+			ui.rows = append(ui.rows, uiRow{text: sanitizeLine(formatExitedLine(info, ui.opts)), style: "\033[90m"})
+		}
+	}
+
+	// Keep the cursor on the same process across refreshes when possible
+	for i, row := range ui.rows {
+		if row.node != nil && row.node.Process.Pid == ui.cursorPid {
+			ui.cursor = i
+			return
+		}
+	}
+	ui.moveCursor(0)
+}
+
+// addRows appends the row for node and, unless it is collapsed, its children
+func (ui *watchUI) addRows(node *ProcessNode, nextSiblings []*ProcessNode) {
+	// Collapsed nodes show how many processes are hidden right after the tree prefix,
+	// so the marker stays visible when long command lines are truncated
+	text := changeMarker(node) + BuildTreePrefix(node, nextSiblings, ui.opts.flatMode)
+	collapsed := ui.collapsed[node.Process.Pid] && ui.expandable(node)
+	if collapsed {
+		text += fmt.Sprintf("[+%d] ", countDescendants(node))
+	}
+	text += formatNodeLine(getProcessInfo(node), ui.opts)
+	ui.rows = append(ui.rows, uiRow{node: node, text: sanitizeLine(text)})
+
+	if details := nodeDetails(node, ui.opts); len(details) > 0 {
+		indent := "  " + detailPrefix(node, nextSiblings, ui.opts.flatMode)
+		for _, detail := range details {
+			ui.rows = append(ui.rows, uiRow{text: sanitizeLine(indent + detail), style: "\033[2m"})
+		}
+	}
+
+	if collapsed {
+		return
+	}
+	threads := nodeThreads(node, ui.opts)
+	for i, thread := range threads {
+		indent := "  " + threadPrefix(node, nextSiblings, i == len(threads)-1 && len(node.Children) == 0, ui.opts.flatMode)
+		ui.rows = append(ui.rows, uiRow{text: sanitizeLine(indent + thread.String())})
+	}
+	for i, child := range node.Children {
+		ui.addRows(child, node.Children[i+1:])
+	}
+}
+
+// expandable reports whether node has rows below it that can be collapsed: child processes or threads
+func (ui *watchUI) expandable(node *ProcessNode) bool {
+	return len(node.Children) > 0 || len(nodeThreads(node, ui.opts)) > 0
+}
+
+// countDescendants returns the number of processes and threads below node
+func countDescendants(node *ProcessNode) int {
+	count := len(getProcessInfo(node).Tasks)
+	for _, child := range node.Children {
+		count += 1 + countDescendants(child)
+	}
+	return count
+}
+
+// sanitizeLine replaces control characters (such as newlines in command lines) with spaces
+func sanitizeLine(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// moveCursor moves the cursor by delta rows, skipping headers and separators
+func (ui *watchUI) moveCursor(delta int) {
+	if len(ui.rows) == 0 {
+		ui.cursor = 0
+		ui.cursorPid = 0
+		return
+	}
+
+	step := 1
+	if delta < 0 {
+		step = -1
+	}
+
+	cursor := ui.cursor + delta
+	if cursor < 0 {
+		cursor = 0
+		step = 1
+	} else if cursor >= len(ui.rows) {
+		cursor = len(ui.rows) - 1
+		step = -1
+	}
+
+	// Find the nearest process row in the direction of movement, then in the other one
+	for _, dir := range []int{step, -step} {
+		for i := cursor; i >= 0 && i < len(ui.rows); i += dir {
+			if ui.rows[i].node != nil {
+				ui.cursor = i
+				ui.cursorPid = ui.rows[i].node.Process.Pid
+				return
+			}
+		}
+	}
+}
+
+// selectedNode returns the process under the cursor
+func (ui *watchUI) selectedNode() *ProcessNode {
+	if ui.cursor < 0 || ui.cursor >= len(ui.rows) {
+		return nil
+	}
+	return ui.rows[ui.cursor].node
+}
+
+// viewHeight returns the number of rows available for the trees
+func (ui *watchUI) viewHeight() int {
+	// Two header lines and one footer line
+	if h := ui.height - 3; h > 0 {
+		return h
+	}
+	return 1
+}
+
+// handleKey applies a key press. It reports whether to quit and whether to refresh immediately.
+func (ui *watchUI) handleKey(k rune) (bool, bool) {
+	if ui.prompt != nil {
+		return ui.handlePromptKey(k)
+	}
+
+	ui.message = ""
+	switch k {
+	case 'q', keyCtrlC:
+		return true, false
+	case keyUp, 'k':
+		ui.moveCursor(-1)
+	case keyDown, 'j':
+		ui.moveCursor(1)
+	case keyPageUp:
+		ui.moveCursor(-ui.viewHeight())
+	case keyPageDown:
+		ui.moveCursor(ui.viewHeight())
+	case keyHome, 'g':
+		ui.moveCursor(-len(ui.rows))
+	case keyEnd, 'G':
+		ui.moveCursor(len(ui.rows))
+	case keyLeft, 'h':
+		node := ui.selectedNode()
+		if node == nil {
+			break
+		}
+		if ui.expandable(node) && !ui.collapsed[node.Process.Pid] {
+			ui.collapsed[node.Process.Pid] = true
+			ui.buildRows()
+		} else if node.Parent != nil {
+			// Already collapsed or a leaf: jump to the parent
+			ui.cursorPid = node.Parent.Process.Pid
+			ui.buildRows()
+		}
+	case keyRight, 'l':
+		if node := ui.selectedNode(); node != nil && ui.collapsed[node.Process.Pid] {
+			delete(ui.collapsed, node.Process.Pid)
+			ui.buildRows()
+		}
+	case ' ', keyEnter:
+		if node := ui.selectedNode(); node != nil && ui.expandable(node) {
+			ui.collapsed[node.Process.Pid] = !ui.collapsed[node.Process.Pid]
+			ui.buildRows()
+		}
+	case 's':
+		node := ui.selectedNode()
+		if node == nil {
+			break
+		}
+		pid := node.Process.Pid
+		if node.exited {
+			ui.message = fmt.Sprintf("PID %d has exited", pid)
+			break
+		}
+		ui.prompt = &uiPrompt{
+			label: fmt.Sprintf("Signal for PID %d (term, hup, int, kill or number): ", pid),
+			submit: func(value string) {
+				ui.sendSignal(pid, value)
+			},
+		}
+	case '/':
+		ui.prompt = &uiPrompt{
+			label: "Targets (PID, :port or pattern): ",
+			value: strings.Join(ui.inputs, " "),
+			submit: func(value string) {
+				ui.setInputs(strings.Fields(value))
+			},
+		}
+	case '+', '=':
+		ui.interval += time.Second
+		ui.message = fmt.Sprintf("Refresh interval set to %s", ui.interval)
+		return false, true
+	case '-', '_':
+		if ui.interval > time.Second {
+			ui.interval -= time.Second
+		}
+		ui.message = fmt.Sprintf("Refresh interval set to %s", ui.interval)
+		return false, true
+	case 'r':
+		return false, true
+	}
+	return false, false
+}
+
+// handlePromptKey edits the active prompt
+func (ui *watchUI) handlePromptKey(k rune) (bool, bool) {
+	switch k {
+	case keyCtrlC, keyEscape:
+		ui.prompt = nil
+	case keyEnter:
+		prompt := ui.prompt
+		ui.prompt = nil
+		prompt.submit(prompt.value)
+		return false, true
+	case keyBackspace:
+		if r := []rune(ui.prompt.value); len(r) > 0 {
+			ui.prompt.value = string(r[:len(r)-1])
+		}
+	default:
+		if k <= unicode.MaxRune && unicode.IsPrint(k) {
+			ui.prompt.value += string(k)
+		}
+	}
+	return false, false
+}
+
+// sendSignal sends the signal named by value to a single PID
+func (ui *watchUI) sendSignal(pid int32, value string) {
+	sig, err := parseSignal(strings.TrimSpace(value))
+	if err != nil {
+		ui.message = fmt.Sprintf("Error parsing signal: %v", err)
+		return
+	}
+
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		ui.message = fmt.Sprintf("Warning: Could not create process object for PID %d: %v", pid, err)
+		return
+	}
+
+	if err := proc.SendSignal(sig); err != nil {
+		ui.message = fmt.Sprintf("Warning: Could not send signal to PID %d: %v", pid, err)
+	} else {
+		ui.message = fmt.Sprintf("Sent signal %d to PID %d", sig, pid)
+	}
+}
+
+// setInputs replaces the watched targets after validating them
+func (ui *watchUI) setInputs(inputs []string) {
+	if len(inputs) == 0 && !ui.opts.selectsWithoutInputs() {
+		ui.message = "At least one target PID/port/name is required"
+		return
+	}
+	if _, err := parseInputs(inputs, ui.opts); err != nil {
+		ui.message = err.Error()
+		return
+	}
+	ui.inputs = inputs
+	ui.cursorPid = 0
+	ui.opts.changes.reset()
+}
+
+// draw renders the whole view in a single write, overwriting the previous frame
+func (ui *watchUI) draw() {
+	ui.width, ui.height = 80, 24
+	if w, h, err := terminalSize(os.Stdout); err == nil && w > 0 && h > 0 {
+		ui.width, ui.height = w, h
+	}
+
+	// Scroll so the cursor stays visible
+	viewHeight := ui.viewHeight()
+	if ui.cursor < ui.offset {
+		ui.offset = ui.cursor
+	} else if ui.cursor >= ui.offset+viewHeight {
+		ui.offset = ui.cursor - viewHeight + 1
+	}
+	if maxOffset := len(ui.rows) - viewHeight; ui.offset > maxOffset {
+		ui.offset = maxOffset
+	}
+	if ui.offset < 0 {
+		ui.offset = 0
+	}
+
+	ui.out.WriteString("\033[H")
+	status := fmt.Sprintf("Every %s: psjungle%s", ui.interval, watchCommandArgs(ui.opts, ui.inputs, ui.useKill, ui.killValue))
+	ui.writeLine(status, "")
+	ui.writeLine(ui.message, "\033[33m")
+
+	for i := ui.offset; i < ui.offset+viewHeight; i++ {
+		if i >= len(ui.rows) {
+			ui.writeLine("", "")
+			continue
+		}
+		row := ui.rows[i]
+		style := row.style
+		if row.node != nil {
+			style = nodeStyle(row.node)
+		}
+		if i == ui.cursor {
+			style += "\033[7m"
+		}
+		ui.writeLine(row.text, style)
+	}
+
+	footer := watchHelpText
+	if ui.prompt != nil {
+		footer = ui.prompt.label + ui.prompt.value + "_"
+	}
+	ui.out.WriteString("\033[7m" + truncateLine(footer, ui.width) + "\033[K\033[0m")
+	ui.out.Flush()
+}
+
+// writeLine writes one screen line, truncated to the terminal width
+func (ui *watchUI) writeLine(text string, style string) {
+	line := truncateLine(text, ui.width)
+	if style != "" {
+		line = style + line + "\033[0m"
+	}
+	ui.out.WriteString(line + "\033[K\r\n")
+}
+
+// truncateLine cuts s to at most width characters
+func truncateLine(s string, width int) string {
+	r := []rune(s)
+	if width > 0 && len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
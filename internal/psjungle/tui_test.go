@@ -0,0 +1,178 @@
+package psjungle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []rune
+	}{
+		{"letters", "jk", []rune{'j', 'k'}},
+		{"arrows", "\033[A\033[B\033[C\033[D", []rune{keyUp, keyDown, keyRight, keyLeft}},
+		{"application mode arrows", "\033OA\033OB", []rune{keyUp, keyDown}},
+		{"paging", "\033[5~\033[6~", []rune{keyPageUp, keyPageDown}},
+		{"home and end", "\033[H\033[F\033[1~\033[4~", []rune{keyHome, keyEnd, keyHome, keyEnd}},
+		{"enter", "\r\n", []rune{keyEnter, keyEnter}},
+		{"backspace", "\x7f\b", []rune{keyBackspace, keyBackspace}},
+		{"ctrl-c", "\x03", []rune{keyCtrlC}},
+		{"lone escape", "\033", []rune{keyEscape}},
+		{"unknown sequence drops the rest", "j\033[Zk", []rune{'j'}},
+		{"multibyte", "é/", []rune{'é', '/'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeys(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// testWatchUI returns a view with room for two rows of two trees:
+//
+//	1
+//	└── 2
+//	    ├── 3
+//	    └── 4
+//	10
+func testWatchUI() *watchUI {
+	first := testNode(processInfo{Pid: 1}, testNode(processInfo{Pid: 2}, testNode(processInfo{Pid: 3}), testNode(processInfo{Pid: 4})))
+	second := testNode(processInfo{Pid: 10})
+	ui := &watchUI{
+		opts:      &options{changes: newChangeTracker()},
+		trees:     []*targetTree{{pid: 2, root: first}, {pid: 10, root: second}},
+		multiple:  true,
+		collapsed: make(map[int32]bool),
+		height:    5,
+	}
+	ui.buildRows()
+	return ui
+}
+
+func TestWatchUICursorBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []rune
+		want int32
+	}{
+		{"starts on the first process", nil, 1},
+		{"down", []rune{'j'}, 2},
+		{"skips headers and separators", []rune{'j', 'j', 'j', 'j'}, 10},
+		{"stops at the last process", []rune{'j', 'j', 'j', 'j', 'j', 'j'}, 10},
+		{"stops at the first process", []rune{'j', 'k', 'k', keyUp}, 1},
+		{"end", []rune{keyEnd}, 10},
+		{"home", []rune{'G', keyHome}, 1},
+		{"page down", []rune{keyPageDown}, 3},
+		{"page up skips the separator", []rune{keyEnd, keyPageUp}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ui := testWatchUI()
+			for _, k := range tt.keys {
+				ui.handleKey(k)
+			}
+			if node := ui.selectedNode(); node == nil || node.Process.Pid != tt.want {
+				t.Errorf("expected the cursor on PID %d, got row %d (%+v)", tt.want, ui.cursor, ui.rows[ui.cursor])
+			}
+		})
+	}
+}
+
+// visiblePids returns the processes shown by the view, in order
+func visiblePids(ui *watchUI) []int32 {
+	var pids []int32
+	for _, row := range ui.rows {
+		if row.node != nil {
+			pids = append(pids, row.node.Process.Pid)
+		}
+	}
+	return pids
+}
+
+func TestWatchUICollapseAndExpand(t *testing.T) {
+	ui := testWatchUI()
+	ui.handleKey('j') // PID 2
+
+	ui.handleKey(keyLeft)
+	if got, want := visiblePids(ui), []int32{1, 2, 10}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after collapsing PID 2: got %v, want %v", got, want)
+	}
+	if row := ui.rows[ui.cursor]; row.node.Process.Pid != 2 || !strings.Contains(row.text, "[+2] ") {
+		t.Errorf("expected the cursor on collapsed PID 2 with a [+2] marker, got %q", row.text)
+	}
+
+	// Left on a collapsed process jumps to its parent
+	ui.handleKey(keyLeft)
+	if node := ui.selectedNode(); node.Process.Pid != 1 {
+		t.Errorf("expected the cursor to jump to PID 1, got PID %d", node.Process.Pid)
+	}
+
+	ui.handleKey('j')
+	ui.handleKey(keyRight)
+	if got, want := visiblePids(ui), []int32{1, 2, 3, 4, 10}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after expanding PID 2: got %v, want %v", got, want)
+	}
+
+	// Space toggles, and does nothing on a leaf
+	ui.handleKey(' ')
+	if got, want := visiblePids(ui), []int32{1, 2, 10}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after toggling PID 2: got %v, want %v", got, want)
+	}
+	ui.handleKey(keyEnd)
+	ui.handleKey(' ')
+	if len(ui.collapsed) != 1 || !ui.collapsed[2] {
+		t.Errorf("expected only PID 2 to be collapsed, got %v", ui.collapsed)
+	}
+}
+
+func TestWatchUIPromptEditing(t *testing.T) {
+	tests := []struct {
+		name      string
+		keys      []rune
+		want      string
+		submitted bool
+		open      bool
+	}{
+		{"typing", []rune{'a', 'b'}, "nginxab", false, true},
+		{"backspace", []rune{keyBackspace, keyBackspace}, "ngi", false, true},
+		{"backspace removes whole runes", []rune{'é', keyBackspace}, "nginx", false, true},
+		{"backspace on empty value", []rune{keyBackspace, keyBackspace, keyBackspace, keyBackspace, keyBackspace, keyBackspace}, "", false, true},
+		{"ignores special keys", []rune{keyUp, keyPageDown, '\t'}, "nginx", false, true},
+		{"enter submits", []rune{' ', ':', '8', keyEnter}, "nginx :8", true, false},
+		{"escape cancels", []rune{'x', keyEscape}, "", false, false},
+		{"ctrl-c cancels", []rune{keyCtrlC}, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var submitted string
+			var called bool
+			ui := &watchUI{prompt: &uiPrompt{value: "nginx", submit: func(value string) {
+				submitted, called = value, true
+			}}}
+
+			var refresh bool
+			for _, k := range tt.keys {
+				_, refresh = ui.handleKey(k)
+			}
+
+			if (ui.prompt != nil) != tt.open {
+				t.Fatalf("expected the prompt open=%v", tt.open)
+			}
+			if called != tt.submitted || refresh != tt.submitted {
+				t.Fatalf("expected submitted=%v, got submit %v and refresh %v", tt.submitted, called, refresh)
+			}
+			got := submitted
+			if ui.prompt != nil {
+				got = ui.prompt.value
+			}
+			if got != tt.want {
+				t.Errorf("expected value %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,490 @@
+package psjungle
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Where is a compiled --where expression, such as
+// cpu > 50 && rss > 500MB && name =~ "java". It is a Predicate.
+type Where struct {
+	text    string
+	root    whereExpr
+	usesCPU bool
+
+	// sampler measures CPU% over an interval for the cpu field; without it the
+	// lifetime average is used
+	sampler *cpuSampler
+	cpu     map[int32]float64
+}
+
+// whereKind is the type of a field or literal in a --where expression
+type whereKind int
+
+const (
+	whereNumber whereKind = iota
+	whereString
+)
+
+// whereField is a process field that can be used in a --where expression
+type whereField struct {
+	kind whereKind
+	// number or text reads the field; ok is false when it can't be read
+	number func(f *whereFacts) (float64, bool)
+	text   func(f *whereFacts) (string, bool)
+}
+
+// whereFields maps the field names accepted by --where to their readers
+var whereFields = map[string]whereField{
+	"pid": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		return float64(f.proc.Pid), true
+	}},
+	"ppid": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		ppid, err := f.proc.Ppid()
+		return float64(ppid), err == nil
+	}},
+	"uid": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		uids, err := f.proc.Uids()
+		if err != nil {
+			return 0, false
+		}
+		uid, ok := effectiveID(uids)
+		return float64(uid), ok
+	}},
+	"cpu": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		if cpu, ok := f.where.cpu[f.proc.Pid]; ok {
+			return cpu, true
+		}
+		cpu, err := f.proc.CPUPercent()
+		return cpu, err == nil
+	}},
+	"rss": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		mem := f.memory()
+		return float64(mem.RSS), mem.RSS > 0
+	}},
+	"vsz": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		mem := f.memory()
+		return float64(mem.VMS), mem.VMS > 0
+	}},
+	"threads": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		threads, err := f.proc.NumThreads()
+		return float64(threads), err == nil
+	}},
+	"age": {kind: whereNumber, number: func(f *whereFacts) (float64, bool) {
+		createTime, err := f.proc.CreateTime()
+		if err != nil {
+			return 0, false
+		}
+		return time.Since(time.UnixMilli(createTime)).Seconds(), true
+	}},
+	"user": {kind: whereString, text: func(f *whereFacts) (string, bool) {
+		username, err := f.proc.Username()
+		return username, err == nil
+	}},
+	"name": {kind: whereString, text: func(f *whereFacts) (string, bool) {
+		name, err := f.proc.Name()
+		return name, err == nil
+	}},
+	"cmd": {kind: whereString, text: func(f *whereFacts) (string, bool) {
+		cmdline, err := f.proc.Cmdline()
+		return cmdline, err == nil
+	}},
+	"exe": {kind: whereString, text: func(f *whereFacts) (string, bool) {
+		exe, err := f.proc.Exe()
+		return exe, err == nil
+	}},
+}
+
+// whereAliases maps alternative field names to the names in whereFields
+var whereAliases = map[string]string{
+	"mem":     "rss",
+	"memory":  "rss",
+	"cmdline": "cmd",
+	"command": "cmd",
+	"nlwp":    "threads",
+}
+
+// whereFacts holds the process being matched, with the memory info cached
+// since several fields read it. Kernel threads have no memory, so their rss
+// and vsz are treated as unreadable.
+type whereFacts struct {
+	proc  *process.Process
+	where *Where
+	mem   *process.MemoryInfoStat
+	read  bool
+}
+
+// memory returns the memory info of the process, or an empty one when it can't be read
+func (f *whereFacts) memory() *process.MemoryInfoStat {
+	if !f.read {
+		f.mem, _ = f.proc.MemoryInfo()
+		f.read = true
+	}
+	if f.mem == nil {
+		return &process.MemoryInfoStat{}
+	}
+	return f.mem
+}
+
+// whereExpr is a node of a parsed --where expression
+type whereExpr interface {
+	eval(f *whereFacts) bool
+}
+
+type whereAnd struct{ left, right whereExpr }
+type whereOr struct{ left, right whereExpr }
+type whereNot struct{ expr whereExpr }
+
+func (e whereAnd) eval(f *whereFacts) bool { return e.left.eval(f) && e.right.eval(f) }
+func (e whereOr) eval(f *whereFacts) bool  { return e.left.eval(f) || e.right.eval(f) }
+func (e whereNot) eval(f *whereFacts) bool { return !e.expr.eval(f) }
+
+// whereCompare compares a field with a literal. A field that can't be read
+// never matches, whatever the operator.
+type whereCompare struct {
+	field  whereField
+	op     string
+	number float64
+	text   string
+	re     *regexp.Regexp
+}
+
+func (e whereCompare) eval(f *whereFacts) bool {
+	if e.field.kind == whereNumber {
+		value, ok := e.field.number(f)
+		if !ok {
+			return false
+		}
+		switch e.op {
+		case "==":
+			return value == e.number
+		case "!=":
+			return value != e.number
+		case "<":
+			return value < e.number
+		case "<=":
+			return value <= e.number
+		case ">":
+			return value > e.number
+		default:
+			return value >= e.number
+		}
+	}
+
+	value, ok := e.field.text(f)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "==":
+		return value == e.text
+	case "!=":
+		return value != e.text
+	case "=~":
+		return e.re.MatchString(value)
+	default:
+		return !e.re.MatchString(value)
+	}
+}
+
+// CompileWhere parses a --where expression. Comparisons (==, !=, <, <=, >, >=
+// for numbers, ==, !=, =~, !~ for strings) can be combined with &&, ||, ! and
+// parentheses (or and, or, not). Numbers may have a size suffix (500MB, 2G) or
+// a duration suffix for age (30s, 5m, 2h, 1d).
+func CompileWhere(text string) (*Where, error) {
+	tokens, err := lexWhere(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where expression: %v", err)
+	}
+
+	p := &whereParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where expression: %v", err)
+	}
+
+	return &Where{text: text, root: root, usesCPU: p.usesCPU}, nil
+}
+
+// String returns the expression as written
+func (w *Where) String() string {
+	return w.text
+}
+
+// Match reports whether the process satisfies the expression
+func (w *Where) Match(proc *process.Process) bool {
+	return w.root.eval(&whereFacts{proc: proc, where: w})
+}
+
+// prepare samples the CPU% of all candidates at once when the expression uses it
+func (w *Where) prepare(procs []*process.Process) {
+	if w.usesCPU && w.sampler.enabled() {
+		w.cpu = w.sampler.percentages(procs)
+	}
+}
+
+// whereToken is a lexical token of a --where expression
+type whereToken struct {
+	kind string // "ident", "number", "string" or "op"
+	text string
+}
+
+// lexWhere splits a --where expression into tokens
+func lexWhere(text string) ([]whereToken, error) {
+	var tokens []whereToken
+	s := text
+	for len(s) > 0 {
+		r := rune(s[0])
+		switch {
+		case unicode.IsSpace(r):
+			s = s[1:]
+		case r == '"' || r == '\'':
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string %s", s)
+			}
+			tokens = append(tokens, whereToken{kind: "string", text: s[1 : end+1]})
+			s = s[end+2:]
+		case unicode.IsDigit(r) || r == '.':
+			n := strings.IndexFunc(s, func(r rune) bool {
+				return !unicode.IsDigit(r) && r != '.' && !unicode.IsLetter(r)
+			})
+			if n < 0 {
+				n = len(s)
+			}
+			tokens = append(tokens, whereToken{kind: "number", text: s[:n]})
+			s = s[n:]
+		case unicode.IsLetter(r) || r == '_':
+			n := strings.IndexFunc(s, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+			})
+			if n < 0 {
+				n = len(s)
+			}
+			word := s[:n]
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, whereToken{kind: "op", text: "&&"})
+			case "or":
+				tokens = append(tokens, whereToken{kind: "op", text: "||"})
+			case "not":
+				tokens = append(tokens, whereToken{kind: "op", text: "!"})
+			default:
+				tokens = append(tokens, whereToken{kind: "ident", text: word})
+			}
+			s = s[n:]
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(s, candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character '%c'", r)
+			}
+			tokens = append(tokens, whereToken{kind: "op", text: op})
+			s = s[len(op):]
+		}
+	}
+	return tokens, nil
+}
+
+// whereParser is a recursive descent parser for --where expressions.
+// || binds looser than &&, which binds looser than !.
+type whereParser struct {
+	tokens  []whereToken
+	pos     int
+	usesCPU bool
+}
+
+// peek returns the next token, or an empty token at the end
+func (p *whereParser) peek() whereToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return whereToken{}
+}
+
+// next consumes and returns the next token
+func (p *whereParser) next() whereToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *whereParser) parseOr() (whereExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "op" && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = whereOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseAnd() (whereExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "op" && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = whereAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseUnary() (whereExpr, error) {
+	t := p.peek()
+	if t.kind == "op" && t.text == "!" {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return whereNot{expr}, nil
+	}
+	if t.kind == "op" && t.text == "(" {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.text != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *whereParser) parseComparison() (whereExpr, error) {
+	t := p.next()
+	if t.kind != "ident" {
+		if t.text == "" {
+			return nil, fmt.Errorf("unexpected end of expression")
+		}
+		return nil, fmt.Errorf("expected a field name, got '%s'", t.text)
+	}
+
+	name := strings.ToLower(t.text)
+	if alias, ok := whereAliases[name]; ok {
+		name = alias
+	}
+	field, ok := whereFields[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field '%s' (available: %s)", t.text, strings.Join(whereFieldNames(), ", "))
+	}
+	if name == "cpu" {
+		p.usesCPU = true
+	}
+
+	op := p.next()
+	if op.kind != "op" {
+		return nil, fmt.Errorf("expected an operator after '%s'", t.text)
+	}
+	value := p.next()
+	if value.kind != "number" && value.kind != "string" && value.kind != "ident" {
+		return nil, fmt.Errorf("expected a value after '%s %s'", t.text, op.text)
+	}
+
+	cmp := whereCompare{field: field, op: op.text}
+	if field.kind == whereNumber {
+		switch op.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("operator '%s' can't be used with numeric field '%s'", op.text, t.text)
+		}
+		if value.kind != "number" {
+			return nil, fmt.Errorf("expected a number for '%s', got '%s'", t.text, value.text)
+		}
+		number, err := parseWhereNumber(value.text)
+		if err != nil {
+			return nil, err
+		}
+		cmp.number = number
+		return cmp, nil
+	}
+
+	// String fields also accept bare words, e.g. user == root
+	cmp.text = value.text
+	switch op.text {
+	case "==", "!=":
+	case "=~", "!~":
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, err
+		}
+		cmp.re = re
+	default:
+		return nil, fmt.Errorf("operator '%s' can't be used with string field '%s'", op.text, t.text)
+	}
+	return cmp, nil
+}
+
+// whereUnits are the suffixes accepted after numbers: sizes in bytes and durations in seconds.
+// Lowercase m means minutes, M means megabytes. KB, MB, GB and TB are the units of the memory
+// columns (see formatMemory), so a process shown with 500MB matches 'rss >= 500MB': 1KB is
+// 1024 bytes and each larger unit is 1000 of the previous one. KiB, MiB, GiB and TiB are binary.
+var whereUnits = map[string]float64{
+	"b": 1, "B": 1,
+	"k": 1 << 10, "K": 1 << 10, "kb": 1 << 10, "KB": 1 << 10, "KiB": 1 << 10,
+	"M": 1e3 << 10, "mb": 1e3 << 10, "MB": 1e3 << 10, "MiB": 1 << 20,
+	"g": 1e6 << 10, "G": 1e6 << 10, "gb": 1e6 << 10, "GB": 1e6 << 10, "GiB": 1 << 30,
+	"t": 1e9 << 10, "T": 1e9 << 10, "tb": 1e9 << 10, "TB": 1e9 << 10, "TiB": 1 << 40,
+	"s": 1, "m": 60, "h": 3600, "d": 86400,
+}
+
+// parseWhereNumber parses a number with an optional size or duration suffix
+func parseWhereNumber(text string) (float64, error) {
+	end := strings.IndexFunc(text, unicode.IsLetter)
+	if end < 0 {
+		end = len(text)
+	}
+	number, err := strconv.ParseFloat(text[:end], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", text)
+	}
+	if suffix := text[end:]; suffix != "" {
+		unit, ok := whereUnits[suffix]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit '%s' in '%s'", suffix, text)
+		}
+		number *= unit
+	}
+	return number, nil
+}
+
+// whereFieldNames returns the sorted list of field names accepted by --where
+func whereFieldNames() []string {
+	names := make([]string, 0, len(whereFields))
+	for name := range whereFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,51 @@
+package psjungle
+
+import "testing"
+
+func TestParseWhereNumber(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"42", 42},
+		{"1.5", 1.5},
+		{"512B", 512},
+		{"2K", 2048},
+		{"2KB", 2048},
+		{"2KiB", 2048},
+		{"500MB", 500 * 1000 * 1024},
+		{"500M", 500 * 1000 * 1024},
+		{"1MiB", 1 << 20},
+		{"1GB", 1000 * 1000 * 1024},
+		{"1GiB", 1 << 30},
+		{"1TB", 1000 * 1000 * 1000 * 1024},
+		{"1TiB", 1 << 40},
+		{"90s", 90},
+		{"5m", 300},
+		{"2h", 7200},
+		{"1d", 86400},
+	}
+	for _, tt := range tests {
+		got, err := parseWhereNumber(tt.text)
+		if err != nil {
+			t.Errorf("parseWhereNumber(%q) failed: %v", tt.text, err)
+		} else if got != tt.want {
+			t.Errorf("parseWhereNumber(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWhereSizeMatchesDisplayedMemory(t *testing.T) {
+	// A process shown with 500MB compares equal to 500MB
+	rss := uint64(500 * 1000 * 1024)
+	if shown := formatMemory(rss / 1024); shown != "500.0MB" {
+		t.Fatalf("expected %d bytes to be shown as 500.0MB, got %s", rss, shown)
+	}
+	limit, err := parseWhereNumber("500MB")
+	if err != nil {
+		t.Fatalf("parseWhereNumber failed: %v", err)
+	}
+	if float64(rss) != limit {
+		t.Errorf("expected 500MB to be %d bytes, got %v", rss, limit)
+	}
+}
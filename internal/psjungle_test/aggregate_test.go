@@ -0,0 +1,34 @@
+package psjungle_test
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRunAggregateJSON(t *testing.T) {
+	// The shell waits for its sleep child, so the target has a subtree to sum
+	cmd := startProcess(t, "sh", "-c", "sleep 10; echo psjungle_test_aggregate")
+
+	trees := runJSON(t, "--aggregate", strconv.Itoa(cmd.Process.Pid))
+	if len(trees) != 1 {
+		t.Fatalf("expected a single tree, got %d", len(trees))
+	}
+	target := findNode(trees[0].Tree, cmd.Process.Pid)
+	if target == nil || len(target.Children) == 0 {
+		t.Fatalf("expected PID %d with its sleep child in the tree", cmd.Process.Pid)
+	}
+	if target.Total == nil {
+		t.Fatalf("expected subtree totals on the target")
+	}
+
+	want := target.RSS
+	for _, child := range target.Children {
+		want += child.RSS
+	}
+	if target.Total.RSS != want {
+		t.Errorf("expected total RSS %d, got %d", want, target.Total.RSS)
+	}
+	if trees[0].Tree.Pid != cmd.Process.Pid && trees[0].Tree.Total != nil {
+		t.Errorf("expected no totals on ancestors of the target")
+	}
+}
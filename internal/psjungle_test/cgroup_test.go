@@ -0,0 +1,25 @@
+package psjungle_test
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRunCgroupJSON(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("cgroups are only read on Linux")
+	}
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		t.Skipf("cgroups not available: %v", err)
+	}
+
+	pid := os.Getpid()
+	self := runJSONNode(t, pid, "--group-by-cgroup", strconv.Itoa(pid))
+	if self.Cgroup == "" || !strings.Contains(string(data), ":"+self.Cgroup+"\n") {
+		t.Errorf("expected one of the cgroups in %q, got %q", data, self.Cgroup)
+	}
+}
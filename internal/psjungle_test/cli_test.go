@@ -1,8 +1,18 @@
 package psjungle_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/shirou/gopsutil/v3/process"
 	"github.com/urfave/cli/v2"
 
 	"psjungle/internal/psjungle"
@@ -56,3 +66,443 @@ func TestRunInvalidPort(t *testing.T) {
 		t.Fatalf("expected exit code 1, got %d", exitErr.ExitCode())
 	}
 }
+
+func TestRunInvalidOutputFormat(t *testing.T) {
+	expectExitError(t, "psjungle", "-o", "xml", "1")
+}
+
+func TestRunInvalidColumns(t *testing.T) {
+	expectExitError(t, "psjungle", "--columns", "pid,bogus", "1")
+	expectExitError(t, "psjungle", "--columns", "pid", "--format", "{{.Pid}}", "1")
+}
+
+func TestRunInvalidFormat(t *testing.T) {
+	expectExitError(t, "psjungle", "--format", "{{.NoSuchField}}", "1")
+	expectExitError(t, "psjungle", "--format", "{{.Pid", "1")
+}
+
+func TestRunInvalidSortKey(t *testing.T) {
+	expectExitError(t, "psjungle", "--sort", "size", "1")
+}
+
+func TestRunFormatTemplate(t *testing.T) {
+	pid := os.Getpid()
+	output := captureStdout(t, func() {
+		args := []string{"psjungle", "--flat", "--format", "pid={{.Pid}} threads={{.Threads}}", strconv.Itoa(pid)}
+		if err := psjungle.NewApp().Run(args); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "pid="+strconv.Itoa(pid)+" threads=") {
+		t.Fatalf("expected templated line for PID %d, got %q", pid, output)
+	}
+}
+
+func TestRunMixedInputs(t *testing.T) {
+	uniqueID := "psjungle_test_mixed_24680"
+	cmd := startProcess(t, "sh", "-c", "sleep 10; echo "+uniqueID)
+
+	// A PID and a pattern can be given together; the pattern no longer has to be a PID.
+	// Both inputs resolve to the same process, so only one tree is shown.
+	trees := runJSON(t, strconv.Itoa(cmd.Process.Pid), uniqueID)
+	if len(trees) != 1 || trees[0].Target != cmd.Process.Pid {
+		t.Fatalf("expected a single tree for PID %d, got %+v", cmd.Process.Pid, trees)
+	}
+}
+
+func TestRunMixedInputsInvalidPort(t *testing.T) {
+	expectExitError(t, "psjungle", "1", ":notaport")
+}
+
+func TestRunOwnerFilter(t *testing.T) {
+	uniqueID := "psjungle_test_owner_13579"
+	cmd := startProcess(t, "sh", "-c", "sleep 10; echo "+uniqueID)
+
+	trees := runJSON(t, "--uid", strconv.Itoa(os.Getuid()), uniqueID)
+	if len(trees) != 1 || trees[0].Target != cmd.Process.Pid {
+		t.Fatalf("expected a single tree for PID %d, got %+v", cmd.Process.Pid, trees)
+	}
+}
+
+func TestRunTopSelection(t *testing.T) {
+	uniqueID := "psjungle_test_top_24680"
+	started := make(map[int]bool)
+	for i := 0; i < 3; i++ {
+		cmd := startProcess(t, "sh", "-c", "sleep 10; echo "+uniqueID)
+		started[cmd.Process.Pid] = true
+	}
+
+	for _, by := range []string{"cpu", "mem"} {
+		trees := runJSON(t, "--top", "1", "--by", by, uniqueID)
+		if len(trees) != 1 {
+			t.Fatalf("--by %s: expected a single tree, got %+v", by, trees)
+		}
+		if !started[trees[0].Target] {
+			t.Errorf("--by %s: unexpected target %d", by, trees[0].Target)
+		}
+	}
+}
+
+func TestRunInvalidTop(t *testing.T) {
+	expectExitError(t, "psjungle", "--top", "0")
+	expectExitError(t, "psjungle", "--top", "5", "--by", "io")
+	expectExitError(t, "psjungle", "--by", "mem", "1")
+}
+
+func TestRunUnknownUser(t *testing.T) {
+	expectExitError(t, "psjungle", "--user", "psjungle-no-such-user", "1")
+}
+
+func TestRunInvalidUID(t *testing.T) {
+	expectExitError(t, "psjungle", "--uid", "abc", "1")
+}
+
+func TestRunPortRangeAndList(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	for _, spec := range []string{
+		fmt.Sprintf(":%d-%d", port-1, port+1),
+		fmt.Sprintf(":1,%d", port),
+	} {
+		found := false
+		for _, tree := range runJSON(t, spec) {
+			if tree.Target == os.Getpid() {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected %s to match PID %d", spec, os.Getpid())
+		}
+	}
+}
+
+func TestRunPortQueryFilters(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	// Connect to ourselves so the port has a client as well
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// UDP sockets are never listening, an unconnected one serves its port
+	udp, err := net.ListenPacket("udp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer udp.Close()
+	udpPort := strconv.Itoa(udp.LocalAddr().(*net.UDPAddr).Port)
+
+	for _, args := range [][]string{
+		{"--proto", "tcp", "--state", "listen", ":" + port},
+		{"--remote", "--state", "ESTABLISHED", ":" + port},
+		{"--proto", "udp", ":" + udpPort},
+		{"--host", "0.0.0.0", "--proto", "udp", ":" + udpPort},
+		{"--host", "127.0.0.1", "--proto", "udp", ":" + udpPort},
+	} {
+		trees := runJSON(t, args...)
+		if len(trees) != 1 || trees[0].Target != os.Getpid() {
+			t.Fatalf("expected %v to match only PID %d, got %+v", args, os.Getpid(), trees)
+		}
+	}
+}
+
+func TestRunHostFilter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	for _, host := range []string{"127.0.0.1", "127.0.0.0/8", "localhost"} {
+		trees := runJSON(t, "--host", host, ":"+port)
+		if len(trees) != 1 || trees[0].Target != os.Getpid() {
+			t.Fatalf("expected --host %s to match PID %d, got %+v", host, os.Getpid(), trees)
+		}
+	}
+}
+
+func TestRunHostFilterIPv6(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %v", err)
+	}
+	defer ln.Close()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	hosts := []string{"::1", "[::1]", "::1/128"}
+	// The loopback interface is lo on Linux and lo0 elsewhere
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			hosts = append(hosts, iface.Name)
+			break
+		}
+	}
+
+	for _, host := range hosts {
+		trees := runJSON(t, "--host", host, ":"+port)
+		if len(trees) != 1 || trees[0].Target != os.Getpid() {
+			t.Fatalf("expected --host %s to match PID %d, got %+v", host, os.Getpid(), trees)
+		}
+	}
+}
+
+func TestRunHostFilterWildcard(t *testing.T) {
+	ln, err := net.Listen("tcp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	// A socket bound to all interfaces accepts connections on every address, so any
+	// --host matches it, and the wildcard hosts only match such sockets
+	for _, host := range []string{"127.0.0.1", "10.0.0.0/8", "0.0.0.0", "::", "[::]", "*"} {
+		trees := runJSON(t, "--host", host, ":"+port)
+		if len(trees) != 1 || trees[0].Target != os.Getpid() {
+			t.Fatalf("expected --host %s to match PID %d, got %+v", host, os.Getpid(), trees)
+		}
+	}
+}
+
+func TestRunInvalidHost(t *testing.T) {
+	expectExitError(t, "psjungle", "--host", "10.0.0.0/99", ":80")
+	expectExitError(t, "psjungle", "--host", "psjungle-no-such-interface", ":80")
+	expectExitError(t, "psjungle", "--remote", "--host", "0.0.0.0", ":80")
+}
+
+func TestRunInvalidProto(t *testing.T) {
+	expectExitError(t, "psjungle", "--proto", "sctp", ":80")
+}
+
+func TestRunInvalidConnectionState(t *testing.T) {
+	expectExitError(t, "psjungle", "--state", "bogus", ":80")
+}
+
+func TestRunInvalidPortRange(t *testing.T) {
+	expectExitError(t, "psjungle", ":9000-8000")
+}
+
+// expectExitError runs the app and checks that it fails with exit code 1
+func expectExitError(t *testing.T, args ...string) {
+	t.Helper()
+
+	originalExiter := cli.OsExiter
+	defer func() { cli.OsExiter = originalExiter }()
+	cli.OsExiter = func(int) {}
+
+	err := psjungle.NewApp().Run(args)
+	if err == nil {
+		t.Fatalf("expected error when running %v", args)
+	}
+
+	exitErr, ok := err.(cli.ExitCoder)
+	if !ok {
+		t.Fatalf("expected cli.ExitCoder, got %T", err)
+	}
+
+	if exitErr.ExitCode() != 1 {
+		t.Fatalf("expected exit code 1, got %d", exitErr.ExitCode())
+	}
+}
+
+func TestRunNoProcessesJSON(t *testing.T) {
+	originalExiter := cli.OsExiter
+	defer func() { cli.OsExiter = originalExiter }()
+	cli.OsExiter = func(int) {}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = psjungle.NewApp().Run([]string{"psjungle", "-o", "json", "name=psjungle_test_no_such_process"})
+	})
+
+	if exitErr, ok := err.(cli.ExitCoder); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+	var trees []json.RawMessage
+	if err := json.Unmarshal([]byte(output), &trees); err != nil || len(trees) != 0 {
+		t.Fatalf("expected an empty JSON array on stdout, got %q (%v)", output, err)
+	}
+}
+
+func TestRunJSONOutput(t *testing.T) {
+	pid := os.Getpid()
+	output := captureStdout(t, func() {
+		if err := psjungle.NewApp().Run([]string{"psjungle", "-o", "json", strconv.Itoa(pid)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	var trees []struct {
+		Target int `json:"target"`
+		Tree   struct {
+			Pid      int  `json:"pid"`
+			IsTarget bool `json:"isTarget"`
+		} `json:"tree"`
+	}
+	if err := json.Unmarshal([]byte(output), &trees); err != nil {
+		t.Fatalf("expected valid JSON output, got error %v for %q", err, output)
+	}
+
+	if len(trees) != 1 {
+		t.Fatalf("expected 1 tree, got %d", len(trees))
+	}
+
+	if trees[0].Target != pid {
+		t.Fatalf("expected target PID %d, got %d", pid, trees[0].Target)
+	}
+}
+
+func TestRunInvalidUnitPattern(t *testing.T) {
+	expectExitError(t, "psjungle", "unit:nginx[")
+}
+
+func TestRunKillTree(t *testing.T) {
+	// The shell waits for its sleep child, so the target has a subtree to signal. A second
+	// sleep keeps the shell from exiting on its own once the first one is killed.
+	cmd := exec.Command("sh", "-c", "sleep 10; sleep 10; echo psjungle_test_kill_tree")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start test process: %v", err)
+	}
+	defer cmd.Process.Kill()
+
+	time.Sleep(100 * time.Millisecond)
+
+	output := captureStdout(t, func() {
+		args := []string{"psjungle", "--sample", "0", "-k", "kill", "--kill-tree", "--kill-order", "leaves", strconv.Itoa(cmd.Process.Pid)}
+		if err := psjungle.NewApp().Run(args); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	var signaled []string
+	for _, line := range strings.Split(output, "\n") {
+		if pid, ok := strings.CutPrefix(line, "Sent signal 9 to PID "); ok {
+			signaled = append(signaled, pid)
+		}
+	}
+	target := strconv.Itoa(cmd.Process.Pid)
+	if len(signaled) != 2 || signaled[0] == target || signaled[1] != target {
+		t.Fatalf("expected the sleep child to be signaled before PID %s, got %q", target, output)
+	}
+
+	if err := cmd.Wait(); err == nil {
+		t.Errorf("expected the shell to be killed")
+	}
+}
+
+func TestRunKillGrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		outcome string
+	}{
+		{"exits on SIGTERM", "sleep 10; echo psjungle_test_kill_grace", "exited after signal 15"},
+		{"ignores SIGTERM", "trap '' TERM; sleep 10; echo psjungle_test_kill_grace", "killed with signal 9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", tt.script)
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("Failed to start test process: %v", err)
+			}
+			defer cmd.Process.Kill()
+
+			time.Sleep(100 * time.Millisecond)
+
+			output := captureStdout(t, func() {
+				args := []string{"psjungle", "--sample", "0", "--kill-tree", "--kill-grace", "500ms", strconv.Itoa(cmd.Process.Pid)}
+				if err := psjungle.NewApp().Run(args); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			})
+
+			report := fmt.Sprintf("PID %d: %s", cmd.Process.Pid, tt.outcome)
+			if !strings.Contains(output, report) {
+				t.Errorf("expected %q in the report, got %q", report, output)
+			}
+		})
+	}
+}
+
+func TestRunKillGraceUnsignaled(t *testing.T) {
+	// SIGTERM to another user's process fails with EPERM, so the target is still
+	// running when psjungle gives up on it
+	if os.Getuid() == 0 {
+		t.Skip("root can signal every process")
+	}
+	uids, err := (&process.Process{Pid: 1}).Uids()
+	if err != nil {
+		t.Skipf("could not read the owner of PID 1: %v", err)
+	}
+	for _, uid := range uids {
+		if int(uid) == os.Getuid() {
+			t.Skip("PID 1 is owned by the test user")
+		}
+	}
+
+	originalExiter := cli.OsExiter
+	defer func() { cli.OsExiter = originalExiter }()
+	cli.OsExiter = func(int) {}
+
+	output := captureStdout(t, func() {
+		err = psjungle.NewApp().Run([]string{"psjungle", "--sample", "0", "--kill-grace", "500ms", "1"})
+	})
+
+	if exitErr, ok := err.(cli.ExitCoder); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+	if report := "PID 1: still running, not signaled"; !strings.Contains(output, report) {
+		t.Errorf("expected %q in the report, got %q", report, output)
+	}
+}
+
+func TestRunInvalidKillOrder(t *testing.T) {
+	expectExitError(t, "psjungle", "--kill-tree", "--kill-order", "random", "1")
+	expectExitError(t, "psjungle", "--kill-order", "leaves", "1")
+	expectExitError(t, "psjungle", "--kill-tree", "1")
+	expectExitError(t, "psjungle", "-w", "1", "--kill-tree", "1")
+}
+
+func TestRunInvalidKillGrace(t *testing.T) {
+	expectExitError(t, "psjungle", "--kill-grace", "0s", "1")
+	expectExitError(t, "psjungle", "--kill-grace", "1s", "-w", "1", "1")
+}
+
+// captureStdout runs fn and returns everything it wrote to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+
+	originalStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = originalStdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
@@ -0,0 +1,20 @@
+package psjungle_test
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSampledCPUOfBusyProcess(t *testing.T) {
+	// A process that just started spinning has a low lifetime average but
+	// should show a high CPU% when sampled over an interval
+	cmd := startProcess(t, "sh", "-c", "while :; do :; done")
+
+	pid := cmd.Process.Pid
+	node := runJSONNode(t, pid, "--sample", "300ms", strconv.Itoa(pid))
+
+	// Leave plenty of room for busy CI machines
+	if node.CPU < 5 {
+		t.Errorf("expected sampled CPU%% of busy loop to be at least 5, got %.1f", node.CPU)
+	}
+}
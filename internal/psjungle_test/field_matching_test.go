@@ -0,0 +1,68 @@
+package psjungle_test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"psjungle/internal/psjungle"
+)
+
+func containsPid(pids []int, pid int) bool {
+	for _, p := range pids {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
+
+func TestByFieldMatching(t *testing.T) {
+	// Start a test process with a known environment and working directory
+	dir := t.TempDir()
+	cmd := exec.Command("sh", "-c", "sleep 10; echo psjungle_test_fields")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PSJUNGLE_TEST_FIELD=production")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start test process: %v", err)
+	}
+
+	// Give it a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	// Make sure we clean up
+	defer cmd.Process.Kill()
+
+	tests := []struct {
+		field, value string
+		strict       bool
+		want         bool
+	}{
+		{"name", "sh", false, true},
+		{"name", "s", false, false}, // the whole name has to match
+		{"name", "s.*", false, true},
+		{"name", "s.*", true, false}, // strict mode compares the exact string
+		{"env", "PSJUNGLE_TEST_FIELD=production", false, true},
+		{"env", "PSJUNGLE_TEST_FIELD=staging", false, false},
+		{"env", "PSJUNGLE_TEST_FIELD", false, true},
+		{"cwd", dir, true, true},
+		{"arg0", "sh", false, true},
+	}
+
+	for _, tt := range tests {
+		pids, err := psjungle.ByField(tt.field, tt.value, tt.strict)
+		if err != nil {
+			t.Fatalf("Error matching %s=%s: %v", tt.field, tt.value, err)
+		}
+		if got := containsPid(pids, cmd.Process.Pid); got != tt.want {
+			t.Errorf("%s=%s (strict %v): expected match %v, got %v", tt.field, tt.value, tt.strict, tt.want, got)
+		}
+	}
+}
+
+func TestByFieldUnknownField(t *testing.T) {
+	if _, err := psjungle.ByField("color", "blue", false); err == nil {
+		t.Fatalf("expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,32 @@
+package psjungle_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunFileLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "held.log")
+	if err := os.WriteFile(path, []byte("psjungle\n"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	cmd := startProcess(t, "sh", "-c", "exec 3<"+path+"; sleep 10")
+
+	trees := runJSON(t, "--files", "@"+path)
+	if len(trees) != 1 || trees[0].Target != cmd.Process.Pid {
+		t.Fatalf("expected a single tree for PID %d, got %+v", cmd.Process.Pid, trees)
+	}
+	target := findNode(trees[0].Tree, cmd.Process.Pid)
+	if target == nil {
+		t.Fatalf("PID %d not found in tree", cmd.Process.Pid)
+	}
+
+	for _, f := range target.Files {
+		if f.Fd == 3 && f.Type == "regular" {
+			return
+		}
+	}
+	t.Fatalf("expected fd 3 to be listed as a regular file, got %+v", target.Files)
+}
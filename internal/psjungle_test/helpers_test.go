@@ -0,0 +1,119 @@
+package psjungle_test
+
+import (
+	"encoding/json"
+	"os/exec"
+	"testing"
+	"time"
+
+	"psjungle/internal/psjungle"
+)
+
+// jsonTree is a tree of the JSON output
+type jsonTree struct {
+	Target int       `json:"target"`
+	Tree   *jsonNode `json:"tree"`
+}
+
+// jsonNode is a process of the JSON output, with the fields checked by the tests
+type jsonNode struct {
+	Pid      int          `json:"pid"`
+	CPU      float64      `json:"cpu"`
+	RSS      uint64       `json:"rss"`
+	PSS      uint64       `json:"pss"`
+	USS      uint64       `json:"uss"`
+	Cgroup   string       `json:"cgroup"`
+	Total    *jsonTotal   `json:"total"`
+	Sockets  []jsonSocket `json:"sockets"`
+	Files    []jsonFile   `json:"files"`
+	Tasks    []jsonTask   `json:"tasks"`
+	Children []*jsonNode  `json:"children"`
+}
+
+type jsonTotal struct {
+	CPU float64 `json:"cpu"`
+	RSS uint64  `json:"rss"`
+	PSS uint64  `json:"pss"`
+}
+
+type jsonSocket struct {
+	Proto string `json:"proto"`
+	Local string `json:"local"`
+	State string `json:"state"`
+}
+
+type jsonFile struct {
+	Fd   uint64 `json:"fd"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+type jsonTask struct {
+	Tid   int    `json:"tid"`
+	State string `json:"state"`
+}
+
+// runJSON runs psjungle with -o json and --sample 0 followed by args (a later --sample
+// overrides it) and returns the decoded trees
+func runJSON(t *testing.T, args ...string) []jsonTree {
+	t.Helper()
+
+	output := captureStdout(t, func() {
+		args := append([]string{"psjungle", "-o", "json", "--sample", "0"}, args...)
+		if err := psjungle.NewApp().Run(args); err != nil {
+			t.Fatalf("unexpected error for %v: %v", args, err)
+		}
+	})
+
+	var trees []jsonTree
+	if err := json.Unmarshal([]byte(output), &trees); err != nil {
+		t.Fatalf("expected valid JSON output for %v, got error %v for %q", args, err, output)
+	}
+	return trees
+}
+
+// runJSONNode runs psjungle like runJSON, expects a single tree and returns the node of pid in it
+func runJSONNode(t *testing.T, pid int, args ...string) *jsonNode {
+	t.Helper()
+
+	trees := runJSON(t, args...)
+	if len(trees) != 1 {
+		t.Fatalf("expected a single tree for %v, got %d", args, len(trees))
+	}
+	node := findNode(trees[0].Tree, pid)
+	if node == nil {
+		t.Fatalf("PID %d not found in the tree for %v", pid, args)
+	}
+	return node
+}
+
+// findNode returns the node of pid in the tree, or nil
+func findNode(node *jsonNode, pid int) *jsonNode {
+	if node == nil || node.Pid == pid {
+		return node
+	}
+	for _, child := range node.Children {
+		if found := findNode(child, pid); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// startProcess starts a command that is killed when the test ends, and gives it
+// a moment to show up in the process table
+func startProcess(t *testing.T, name string, args ...string) *exec.Cmd {
+	t.Helper()
+
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start test process: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	return cmd
+}
@@ -0,0 +1,27 @@
+package psjungle_test
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestRunProportionalMemoryJSON(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("PSS and USS are only read on Linux")
+	}
+
+	pid := os.Getpid()
+	self := runJSONNode(t, pid, "-c", "pid,pss,uss", strconv.Itoa(pid))
+
+	// Shared pages only add a share of themselves to PSS, so it is at least the private USS
+	if self.PSS == 0 || self.USS == 0 || self.USS > self.PSS {
+		t.Errorf("expected 0 < USS <= PSS, got USS %d, PSS %d", self.USS, self.PSS)
+	}
+}
+
+func TestRunInvalidAggregateMem(t *testing.T) {
+	expectExitError(t, "psjungle", "--aggregate", "--aggregate-mem", "vsz", "1")
+	expectExitError(t, "psjungle", "--aggregate-mem", "rss", "1")
+}
@@ -0,0 +1,31 @@
+package psjungle_test
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestRunNamespaceTarget(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("PID namespaces are only read on Linux")
+	}
+
+	cmd := startProcess(t, "sleep", "10")
+	link, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", cmd.Process.Pid))
+	if err != nil {
+		t.Skipf("PID namespace not readable: %v", err)
+	}
+
+	// The test runs in a single namespace, so the PID inside it is the same
+	trees := runJSON(t, fmt.Sprintf("ns:%s:%d", link, cmd.Process.Pid))
+	if len(trees) != 1 || trees[0].Target != cmd.Process.Pid {
+		t.Fatalf("expected a single tree for PID %d, got %+v", cmd.Process.Pid, trees)
+	}
+}
+
+func TestRunInvalidNamespace(t *testing.T) {
+	expectExitError(t, "psjungle", "ns:abc")
+	expectExitError(t, "psjungle", "ns:4026531836:0")
+}
@@ -0,0 +1,27 @@
+package psjungle_test
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestRunSocketsJSON(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	local := ln.Addr().String()
+
+	pid := os.Getpid()
+	target := runJSONNode(t, pid, "--sockets", strconv.Itoa(pid))
+
+	for _, s := range target.Sockets {
+		if s.Local == local && s.Proto == "tcp" && s.State == "LISTEN" {
+			return
+		}
+	}
+	t.Fatalf("expected listening socket %s on PID %d, got %+v", local, pid, target.Sockets)
+}
@@ -0,0 +1,34 @@
+package psjungle_test
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestRunThreadsJSON(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("threads are only listed on Linux")
+	}
+
+	// The Go runtime always runs several threads, so the test process has leaves
+	pid := os.Getpid()
+	self := runJSONNode(t, pid, "--threads", strconv.Itoa(pid))
+	if len(self.Tasks) < 2 {
+		t.Fatalf("expected the threads of PID %d, got %+v", pid, self.Tasks)
+	}
+
+	mainThread := false
+	for _, task := range self.Tasks {
+		if task.Tid == pid {
+			mainThread = true
+		}
+		if task.State == "" {
+			t.Errorf("expected a state for thread %d", task.Tid)
+		}
+	}
+	if !mainThread {
+		t.Errorf("expected the main thread %d among %+v", pid, self.Tasks)
+	}
+}
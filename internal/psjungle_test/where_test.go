@@ -0,0 +1,79 @@
+package psjungle_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"psjungle/internal/psjungle"
+)
+
+func TestCompileWhereMatchesCurrentProcess(t *testing.T) {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		t.Fatalf("Failed to get current process: %v", err)
+	}
+	pid := os.Getpid()
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{fmt.Sprintf("pid == %d", pid), true},
+		{fmt.Sprintf("pid != %d", pid), false},
+		{fmt.Sprintf("pid == %d && rss > 1KB", pid), true},
+		{fmt.Sprintf("pid == %d and rss > 1TB", pid), false},
+		{fmt.Sprintf("rss > 1TB || pid == %d", pid), true},
+		{fmt.Sprintf("!(pid == %d)", pid), false},
+		{fmt.Sprintf("not pid == %d", pid), false},
+		{`name =~ "."`, true},
+		{`name !~ "."`, false},
+		{`name == "psjungle-no-such-name"`, false},
+		{"age < 1d && age >= 0s", true},
+		{"threads >= 1", true},
+	}
+
+	for _, tt := range tests {
+		where, err := psjungle.CompileWhere(tt.expr)
+		if err != nil {
+			t.Fatalf("CompileWhere(%q) failed: %v", tt.expr, err)
+		}
+		if got := where.Match(proc); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestCompileWhereErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"color == red",
+		"cpu =~ 1",
+		"name > 3",
+		"rss > 5XB",
+		"(cpu > 1",
+		"cpu >",
+		`name == "unterminated`,
+		"cpu > 1 cpu",
+		`name =~ "("`,
+	} {
+		if _, err := psjungle.CompileWhere(expr); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestFilterPids(t *testing.T) {
+	pid := os.Getpid()
+	where, err := psjungle.CompileWhere(fmt.Sprintf("pid == %d", pid))
+	if err != nil {
+		t.Fatalf("CompileWhere failed: %v", err)
+	}
+
+	pids := psjungle.FilterPids([]int{1, pid}, where)
+	if len(pids) != 1 || pids[0] != pid {
+		t.Fatalf("expected only PID %d, got %v", pid, pids)
+	}
+}